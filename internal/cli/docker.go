@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/lu-zhengda/whport/internal/docker"
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+// lookupPublished asks the Docker daemon which container publishes the
+// port held by a Docker proxy process. It returns nil without error when
+// the entry is not a Docker proxy or no container publishes the port, and
+// with --host, since only the local daemon is reachable.
+func lookupPublished(ctx context.Context, e port.PortEntry) (*docker.Published, error) {
+	if hostFlag != "" || e.Protocol == port.Unix || !docker.IsProxy(e.Process) {
+		return nil, nil
+	}
+	return docker.NewClient("").FindPublished(ctx, e.Port, string(e.Protocol), e.LocalAddr)
+}
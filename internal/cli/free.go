@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/spf13/cobra"
+)
+
+var (
+	freeCount    int
+	freeRange    string
+	freeNear     string
+	freeFamily   string
+	freeProtocol string
+)
+
+var freeCmd = &cobra.Command{
+	Use:   "free",
+	Short: "Find free ports",
+	Long: `Print ports that are free to listen on, one per line.
+
+Each candidate is tested by binding it on all interfaces, and ports that a
+scan finds in use, including in other network namespaces, are skipped.
+Ports below 1024, the ephemeral range and ports reserved in
+ip_local_reserved_ports are never returned.`,
+	Example: `  whport free
+  whport free --near 3000
+  whport free -n 3 --range 8000-8999,!8080
+  PORT=$(whport free --near 5173)`,
+	Args: cobra.NoArgs,
+	RunE: runFree,
+}
+
+func init() {
+	freeCmd.Flags().IntVarP(&freeCount, "count", "n", 1, "Number of ports to find")
+	freeCmd.Flags().StringVar(&freeRange, "range", "", "Ports to choose from, e.g. 3000-3999,!3306")
+	freeCmd.Flags().StringVar(&freeNear, "near", "", "Preferred port or service name; the closest free ports are returned")
+	freeCmd.Flags().StringVar(&freeFamily, "family", "dual", "Address family to bind: ipv4, ipv6 or dual")
+	freeCmd.Flags().StringVar(&freeProtocol, "protocol", "tcp", "Protocol to bind: tcp or udp")
+}
+
+func runFree(cmd *cobra.Command, args []string) error {
+	if hostFlag != "" {
+		return fmt.Errorf("free is not supported with --host")
+	}
+	if freeCount < 1 {
+		return fmt.Errorf("invalid --count %d: must be at least 1", freeCount)
+	}
+
+	opts := port.FreeOptions{
+		Count:    freeCount,
+		Protocol: port.Protocol(strings.ToUpper(freeProtocol)),
+	}
+	switch strings.ToLower(freeFamily) {
+	case "ipv4", "4":
+		opts.Family = port.IPv4
+	case "ipv6", "6":
+		opts.Family = port.IPv6
+	case "dual", "both":
+		opts.Family = port.DualStack
+	default:
+		return fmt.Errorf("invalid --family %q (use ipv4, ipv6 or dual)", freeFamily)
+	}
+	if opts.Protocol != port.TCP && opts.Protocol != port.UDP {
+		return fmt.Errorf("invalid --protocol %q (use tcp or udp)", freeProtocol)
+	}
+	if freeRange != "" {
+		set, err := port.ParsePortSet(freeRange, services())
+		if err != nil {
+			return fmt.Errorf("invalid --range: %w", err)
+		}
+		opts.Ports = set
+	}
+	if freeNear != "" {
+		near, err := services().ParsePort(freeNear)
+		if err != nil {
+			return fmt.Errorf("invalid --near: %w", err)
+		}
+		opts.Near = near
+	}
+
+	ctx, cancel := commandContext(context.Background())
+	defer cancel()
+
+	// Binding finds ports taken in this network namespace; the scan also
+	// covers containers and other namespaces.
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to scan ports: %w", err)
+	}
+	inUse := make(map[int]bool)
+	for _, e := range snap.Entries {
+		if e.Protocol == opts.Protocol {
+			inUse[e.Port] = true
+		}
+	}
+	opts.InUse = func(p int) bool { return inUse[p] }
+
+	ports, ranges, err := port.FindFree(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find free ports: %w", err)
+	}
+
+	// Print what was found even if it falls short, then fail.
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(ports); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range ports {
+			fmt.Println(p)
+		}
+	}
+
+	if len(ports) < freeCount {
+		skipped := "ports below 1024 and the ephemeral range " + ranges.Ephemeral
+		if ranges.Reserved != "" {
+			skipped += " and reserved ports " + ranges.Reserved
+		}
+		return fmt.Errorf("found %d of %d free ports (%s are skipped)", len(ports), freeCount, skipped)
+	}
+	return nil
+}
@@ -84,11 +84,18 @@ func runHistoryShow(cmd *cobra.Command, args []string) error {
 }
 
 func runHistoryRecord(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx, cancel := commandContext(context.Background())
+	defer cancel()
+	if hostFlag != "" {
+		return fmt.Errorf("history only records this machine's ports; --host is not supported")
+	}
 	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
 
-	entries, err := scanner.ListPorts(ctx)
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to scan ports: %w", err)
 	}
@@ -98,8 +105,8 @@ func runHistoryRecord(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create history store: %w", err)
 	}
 
-	now := time.Now()
-	events, err := store.Record(entries, now)
+	now := snap.Time
+	events, err := store.Record(snap.Entries, now)
 	if err != nil {
 		return fmt.Errorf("failed to record history: %w", err)
 	}
@@ -10,73 +10,150 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/whport/internal/docker"
 	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/probe"
 	"github.com/lu-zhengda/whport/internal/process"
 )
 
 var infoCmd = &cobra.Command{
-	Use:   "info <port>",
+	Use:   "info <port|socket-path>...",
 	Short: "Detailed info about a port and its process",
-	Long:  "Display detailed information about the process listening on the specified port.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runInfo,
+	Long: `Display detailed information about the process listening on the specified
+port or Unix socket path.
+
+Several ports, ranges and service names can be given at once, as separate
+arguments or as one list such as 3000-3999,!3306. Each listener on them is
+described in turn, and --json prints an array.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runInfo,
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&probeListeners, "probe", false, "Connect to the listener to identify its protocol")
 }
 
 func runInfo(cmd *cobra.Command, args []string) error {
-	portNum, err := strconv.Atoi(args[0])
+	ctx, cancel := commandContext(context.Background())
+	defer cancel()
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
 	if err != nil {
-		return fmt.Errorf("invalid port number: %w", err)
+		return err
 	}
-
-	ctx := context.Background()
-	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
-	manager := process.NewRealManager(runner)
-
-	entries, err := scanner.FindByPort(ctx, portNum)
+	snap, where, err := findTarget(ctx, scanner, args)
 	if err != nil {
-		return fmt.Errorf("failed to find processes on port %d: %w", portNum, err)
+		return err
 	}
 
-	// Find the LISTEN entry.
-	var target *port.PortEntry
-	for _, e := range entries {
-		if e.State == "LISTEN" && e.Port == portNum {
-			e := e // capture
-			target = &e
-			break
-		}
+	targets := infoTargets(snap.Entries)
+	if len(targets) == 0 {
+		return fmt.Errorf("no process found on %s", where)
 	}
 
-	if target == nil {
-		// Fall back to any entry on that port.
-		for _, e := range entries {
-			if e.Port == portNum {
-				e := e
-				target = &e
-				break
-			}
+	var probes []*probe.Result
+	if probeListeners {
+		probes, err = probeEntries(ctx, targets)
+		if err != nil {
+			return err
 		}
 	}
 
-	if target == nil {
-		return fmt.Errorf("no process found on port %d", portNum)
-	}
+	var out []any
+	for i, target := range targets {
+		// Get detailed process info from the same scan.
+		info, infoErr := process.SnapshotInfo(snap, target.PID)
 
-	// Get detailed process info.
-	info, err := manager.Info(ctx, target.PID)
+		// Published Docker ports are held by a proxy; find the real container.
+		pub, pubErr := lookupPublished(ctx, target)
+
+		var res *probe.Result
+		if probes != nil {
+			res = probes[i]
+		}
 
-	if jsonOutput {
-		return printInfoJSON(target, info)
+		if jsonOutput {
+			out = append(out, infoJSON(&target, info, pub, res))
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printInfoHuman(&target, info, infoErr, pub, pubErr, res)
 	}
 
-	return printInfoHuman(target, info, err)
+	if !jsonOutput {
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if singleTarget(args) {
+		return enc.Encode(out[0])
+	}
+	return enc.Encode(out)
 }
 
-func printInfoHuman(entry *port.PortEntry, info *process.ProcessInfo, infoErr error) error {
-	fmt.Printf("Port:        %d/%s\n", entry.Port, entry.Protocol)
+// infoTargets picks the entry to describe for each port or socket path:
+// the first listener, or else the first entry.
+func infoTargets(entries []port.PortEntry) []port.PortEntry {
+	var targets []port.PortEntry
+	index := make(map[string]int)
+	for _, e := range entries {
+		key := strconv.Itoa(e.Port) + e.Path
+		i, seen := index[key]
+		switch {
+		case !seen:
+			index[key] = len(targets)
+			targets = append(targets, e)
+		case targets[i].State != port.StateListen && e.State == port.StateListen:
+			targets[i] = e
+		}
+	}
+	return targets
+}
+
+func printInfoHuman(entry *port.PortEntry, info *process.ProcessInfo, infoErr error, pub *docker.Published, pubErr error, res *probe.Result) {
+	if entry.Protocol == port.Unix {
+		fmt.Printf("Socket:      %s\n", entry.Path)
+	} else {
+		fmt.Printf("Port:        %d/%s\n", entry.Port, entry.Protocol)
+		if name := services().Name(entry.Port, entry.Protocol); name != "" {
+			fmt.Printf("Service:     %s\n", name)
+		}
+	}
 	fmt.Printf("State:       %s\n", entry.State)
+	if bind := entry.BindSummary(); bind != "" {
+		fmt.Printf("Bind:        %s\n", bind)
+	}
+	if remote := entry.Remote(); remote != "" {
+		fmt.Printf("Remote:      %s\n", remote)
+	}
+	if entry.NetNS != 0 {
+		fmt.Printf("Netns:       %s\n", netnsLabel(entry.NetNS, port.NetNSNames()))
+	}
+	if c := entry.Container; !c.IsZero() {
+		fmt.Printf("Container:   %s (%s)\n", c.ID, c.Runtime)
+		if c.Pod != "" {
+			fmt.Printf("Pod:         %s\n", c.Pod)
+		}
+	}
 	fmt.Printf("Process:     %s (PID %d)\n", entry.Process, entry.PID)
+	if info != nil {
+		if f := process.ForwardFor(info.Forwards, entry.Port); f != nil {
+			fmt.Printf("Forwards to: %s\n", f)
+		}
+	}
+	if pub != nil {
+		fmt.Printf("Published:   %s\n", pub)
+		if pub.Service != "" {
+			fmt.Printf("Compose:     %s/%s\n", pub.Project, pub.Service)
+		}
+	} else if pubErr != nil {
+		fmt.Printf("Published:   (Docker API unavailable: %v)\n", pubErr)
+	}
+	if probeListeners {
+		printProbe(entry, res)
+	}
 
 	if info != nil {
 		fmt.Printf("Command:     %s\n", info.Command)
@@ -109,33 +186,96 @@ func printInfoHuman(entry *port.PortEntry, info *process.ProcessInfo, infoErr er
 			fmt.Printf("Details:     (unavailable: %v)\n", infoErr)
 		}
 	}
+}
 
-	return nil
+// printProbe prints what probing the listener found.
+func printProbe(entry *port.PortEntry, res *probe.Result) {
+	if _, ok := probe.Target(*entry); !ok {
+		fmt.Printf("Probe:       (only local TCP listeners can be probed)\n")
+		return
+	}
+	if res == nil {
+		fmt.Printf("Probe:       (not identified)\n")
+		return
+	}
+	fmt.Printf("Probe:       %s\n", res)
+	if res.Status != "" {
+		fmt.Printf("Status:      %s\n", res.Status)
+	}
+	if res.TLSSubject != "" {
+		left := time.Until(res.TLSExpiry)
+		when := "expired"
+		if left > 0 {
+			when = "in " + formatDuration(left.Truncate(time.Second))
+		}
+		fmt.Printf("Certificate: %s, expires %s (%s)\n",
+			res.TLSSubject, res.TLSExpiry.Format("2006-01-02"), when)
+	}
 }
 
-func printInfoJSON(entry *port.PortEntry, info *process.ProcessInfo) error {
+// infoJSON returns the JSON form of the details about one entry.
+func infoJSON(entry *port.PortEntry, info *process.ProcessInfo, pub *docker.Published, res *probe.Result) any {
+	type jsonPublished struct {
+		ContainerID   string `json:"container_id"`
+		Name          string `json:"name"`
+		Image         string `json:"image"`
+		Project       string `json:"compose_project,omitempty"`
+		Service       string `json:"compose_service,omitempty"`
+		ContainerPort int    `json:"container_port"`
+	}
+	type jsonForward struct {
+		Tool   string `json:"tool"`
+		Target string `json:"target"`
+		Via    string `json:"via,omitempty"`
+	}
 	type jsonInfo struct {
-		Port       int      `json:"port"`
-		Protocol   string   `json:"protocol"`
-		State      string   `json:"state"`
-		PID        int      `json:"pid"`
-		Process    string   `json:"process"`
-		Command    string   `json:"command,omitempty"`
-		User       string   `json:"user"`
-		StartTime  string   `json:"start_time,omitempty"`
-		CPUPercent float64  `json:"cpu_percent,omitempty"`
-		MemoryRSS  int64    `json:"memory_rss_bytes,omitempty"`
-		PPID       int      `json:"ppid,omitempty"`
-		Children   []int    `json:"children,omitempty"`
+		Port       int     `json:"port,omitempty"`
+		Protocol   string  `json:"protocol"`
+		Service    string  `json:"service,omitempty"`
+		Path       string  `json:"path,omitempty"`
+		State      string  `json:"state"`
+		Bind       string  `json:"bind,omitempty"`
+		Family     string  `json:"family,omitempty"`
+		Zone       string  `json:"zone,omitempty"`
+		NetNS      uint64  `json:"netns,omitempty"`
+		Container  string  `json:"container,omitempty"`
+		Runtime    string  `json:"container_runtime,omitempty"`
+		Pod        string  `json:"pod,omitempty"`
+		RemoteAddr string  `json:"remote_addr,omitempty"`
+		RemotePort int     `json:"remote_port,omitempty"`
+		PID        int     `json:"pid"`
+		Process    string  `json:"process"`
+		Command    string  `json:"command,omitempty"`
+		User       string  `json:"user"`
+		StartTime  string  `json:"start_time,omitempty"`
+		CPUPercent float64 `json:"cpu_percent,omitempty"`
+		MemoryRSS  int64   `json:"memory_rss_bytes,omitempty"`
+		PPID       int     `json:"ppid,omitempty"`
+		Children   []int   `json:"children,omitempty"`
+
+		ForwardsTo *jsonForward   `json:"forwards_to,omitempty"`
+		Published  *jsonPublished `json:"published,omitempty"`
+		Probe      *jsonProbe     `json:"probe,omitempty"`
 	}
 
 	out := jsonInfo{
-		Port:     entry.Port,
-		Protocol: string(entry.Protocol),
-		State:    entry.State,
-		PID:      entry.PID,
-		Process:  entry.Process,
-		User:     entry.User,
+		Port:       entry.Port,
+		Protocol:   string(entry.Protocol),
+		Service:    services().Name(entry.Port, entry.Protocol),
+		Path:       entry.Path,
+		State:      string(entry.State),
+		Bind:       entry.LocalAddr,
+		Family:     string(entry.Family),
+		Zone:       entry.Zone,
+		NetNS:      entry.NetNS,
+		Container:  entry.Container.ID,
+		Runtime:    entry.Container.Runtime,
+		Pod:        entry.Container.Pod,
+		RemoteAddr: entry.RemoteAddr,
+		RemotePort: entry.RemotePort,
+		PID:        entry.PID,
+		Process:    entry.Process,
+		User:       entry.User,
 	}
 
 	if info != nil {
@@ -148,11 +288,24 @@ func printInfoJSON(entry *port.PortEntry, info *process.ProcessInfo) error {
 		if !info.StartTime.IsZero() {
 			out.StartTime = info.StartTime.Format(time.RFC3339)
 		}
+		if f := process.ForwardFor(info.Forwards, entry.Port); f != nil {
+			out.ForwardsTo = &jsonForward{Tool: f.Tool, Target: f.Target, Via: f.Via}
+		}
 	}
 
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	return enc.Encode(out)
+	out.Probe = toJSONProbe(res)
+
+	if pub != nil {
+		out.Published = &jsonPublished{
+			ContainerID:   pub.ContainerID,
+			Name:          pub.Name,
+			Image:         pub.Image,
+			Project:       pub.Project,
+			Service:       pub.Service,
+			ContainerPort: pub.ContainerPort,
+		}
+	}
+	return out
 }
 
 func formatDuration(d time.Duration) string {
@@ -3,86 +3,132 @@ package cli
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/lu-zhengda/whport/internal/docker"
 	"github.com/lu-zhengda/whport/internal/port"
 	"github.com/lu-zhengda/whport/internal/process"
 )
 
 var (
-	forceKill  bool
-	signalFlag string
+	forceKill     bool
+	signalFlag    string
+	stopContainer bool
 )
 
 var killCmd = &cobra.Command{
-	Use:   "kill <port>",
+	Use:   "kill [<port|socket-path>...]",
 	Short: "Kill process listening on a port",
-	Long:  "Send a signal to the process listening on the specified port.",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runKill,
+	Long: `Send a signal to the process listening on the specified port or Unix socket path.
+
+Several ports, ranges and service names can be given at once, as separate
+arguments or as one list such as 3000-3999,!3306. Each process listening on
+them is signaled once.
+
+--where selects listeners with an expression instead of, or as well as, the
+ports given, e.g. whport kill --where 'user == me && process == node'.
+
+Ports published by Docker are held by a proxy process. With --stop-container
+the container publishing the port is stopped through the Docker API instead.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && filterWhere == "" {
+			return fmt.Errorf("requires a port, a socket path or --where")
+		}
+		return nil
+	},
+	RunE: runKill,
 }
 
 func init() {
 	killCmd.Flags().BoolVar(&forceKill, "force", false, "Send SIGKILL instead of SIGTERM")
 	killCmd.Flags().StringVar(&signalFlag, "signal", "", "Custom signal to send (e.g. SIGINT, SIGHUP)")
+	killCmd.Flags().BoolVar(&stopContainer, "stop-container", false, "Stop the Docker container publishing the port instead of signaling its proxy")
+	killCmd.Flags().StringVar(&filterWhere, "where", "", "Only kill listeners matching an expression, e.g. 'user == me && port >= 3000'")
 }
 
 func runKill(cmd *cobra.Command, args []string) error {
-	portNum, err := strconv.Atoi(args[0])
+	ctx, cancel := commandContext(context.Background())
+	defer cancel()
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
 	if err != nil {
-		return fmt.Errorf("invalid port number: %w", err)
+		return err
 	}
-
-	ctx := context.Background()
-	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
 	manager := process.NewRealManager(runner)
+	if err := resolveFilters(ctx); err != nil {
+		return err
+	}
 
-	entries, err := scanner.FindByPort(ctx, portNum)
+	snap, where, err := findTarget(ctx, scanner, args)
 	if err != nil {
-		return fmt.Errorf("failed to find processes on port %d: %w", portNum, err)
+		return err
+	}
+	if whereFilter != nil {
+		where += " matching " + whereFilter.String()
 	}
 
 	// Filter to LISTEN entries.
 	var listeners []port.PortEntry
-	for _, e := range entries {
-		if e.State == "LISTEN" && e.Port == portNum {
+	for _, e := range snap.Entries {
+		if e.State == port.StateListen && whereFilter.Match(e) {
 			listeners = append(listeners, e)
 		}
 	}
 
 	if len(listeners) == 0 {
-		return fmt.Errorf("no process listening on port %d", portNum)
+		return fmt.Errorf("no process listening on %s", where)
 	}
 
-	// Kill all listeners on the port (usually just one process).
+	if stopContainer {
+		if hostFlag != "" {
+			return fmt.Errorf("--stop-container is not supported with --host")
+		}
+		return stopPublishers(ctx, listeners, where)
+	}
+
+	// Kill all listeners on the target (usually just one process), each
+	// process once even if it listens on several of the ports.
+	signaled := make(map[int]bool)
 	for _, e := range listeners {
+		if signaled[e.PID] {
+			continue
+		}
+		signaled[e.PID] = true
+		on := targetLabel(e)
 		sig := resolveSignal()
 
 		// Verify the process is what we expect.
 		if !manager.VerifyProcess(ctx, e.PID, e.Process) {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("failed to verify PID %d: %w", e.PID, err)
+			}
 			fmt.Printf("Warning: PID %d may have changed since scan, skipping.\n", e.PID)
 			continue
 		}
 
-		fmt.Printf("Killing %s (PID %d) on port %d with %s...\n",
-			e.Process, e.PID, e.Port, signalName(sig))
+		if pub, err := lookupPublished(ctx, e); err == nil && pub != nil {
+			fmt.Printf("Note: %s is published by container %s; use --stop-container to stop it instead.\n",
+				on, pub.Name)
+		}
+
+		fmt.Printf("Killing %s (PID %d) on %s with %s...\n",
+			e.Process, e.PID, on, signalName(sig))
 
 		if forceKill || sig == syscall.SIGKILL {
-			if err := manager.ForceKill(e.PID); err != nil {
+			if err := manager.ForceKill(ctx, e.PID); err != nil {
 				return fmt.Errorf("failed to kill PID %d: %w", e.PID, err)
 			}
 			fmt.Printf("Sent SIGKILL to PID %d.\n", e.PID)
 		} else if signalFlag != "" {
-			if err := manager.Kill(e.PID, sig); err != nil {
+			if err := manager.Kill(ctx, e.PID, sig); err != nil {
 				return fmt.Errorf("failed to send signal to PID %d: %w", e.PID, err)
 			}
 			fmt.Printf("Sent %s to PID %d.\n", signalName(sig), e.PID)
 		} else {
-			exited, err := manager.GracefulKill(e.PID)
+			exited, err := manager.GracefulKill(ctx, e.PID)
 			if err != nil {
 				return fmt.Errorf("failed to kill PID %d: %w", e.PID, err)
 			}
@@ -98,6 +144,48 @@ func runKill(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// stopPublishers stops the Docker containers whose published ports are
+// held by the given proxy listeners. With --force the daemon kills the
+// container immediately instead of waiting for it to shut down.
+func stopPublishers(ctx context.Context, listeners []port.PortEntry, where string) error {
+	grace := 10 * time.Second
+	if forceKill {
+		grace = 0
+	}
+
+	client := docker.NewClient("")
+	stopped := make(map[string]bool)
+	for _, e := range listeners {
+		pub, err := lookupPublished(ctx, e)
+		if err != nil {
+			return fmt.Errorf("failed to query Docker: %w", err)
+		}
+		if pub == nil || stopped[pub.ContainerID] {
+			continue
+		}
+		stopped[pub.ContainerID] = true
+
+		fmt.Printf("Stopping container %s (%s) publishing %s...\n", pub.Name, pub.Image, targetLabel(e))
+		if err := client.Stop(ctx, pub.ContainerID, grace); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped container %s.\n", pub.Name)
+	}
+
+	if len(stopped) == 0 {
+		return fmt.Errorf("%s is not published by a Docker container", where)
+	}
+	return nil
+}
+
+// targetLabel describes where an entry listens, e.g. "port 3000".
+func targetLabel(e port.PortEntry) string {
+	if e.Protocol == port.Unix {
+		return e.Path
+	}
+	return fmt.Sprintf("port %d", e.Port)
+}
+
 func resolveSignal() syscall.Signal {
 	if forceKill {
 		return syscall.SIGKILL
@@ -4,20 +4,40 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"os/user"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/probe"
+	"github.com/lu-zhengda/whport/internal/query"
 )
 
 var (
 	listAll      bool
-	filterPort   int
+	listUnix     bool
+	filterPort   string
 	filterProc   string
 	filterProto  string
+	filterRemote string
+	filterStates []string
+	filterNetNS  string
+	filterCont   string
+	filterWhere  string
+
+	// portFilter, remoteFilter, stateFilter, netnsFilter and whereFilter
+	// are filterPort, filterRemote, filterStates, filterNetNS and
+	// filterWhere parsed by resolveFilters.
+	portFilter   *port.PortSet
+	remoteFilter *port.RemoteFilter
+	stateFilter  port.StateFilter
+	netnsFilter  uint64
+	whereFilter  *query.Expr
 )
 
 var listCmd = &cobra.Command{
@@ -29,46 +49,210 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().BoolVar(&listAll, "all", false, "Include ESTABLISHED connections (not just LISTEN)")
-	listCmd.Flags().IntVar(&filterPort, "port", 0, "Filter by port number")
+	listCmd.Flags().BoolVar(&listUnix, "unix", false, "List Unix domain sockets instead of ports")
+	listCmd.Flags().StringVar(&filterPort, "port", "", "Filter by ports, ranges and service names, e.g. 3000-3999,postgres,!5432")
 	listCmd.Flags().StringVar(&filterProc, "process", "", "Filter by process name")
 	listCmd.Flags().StringVar(&filterProto, "protocol", "", "Filter by protocol (tcp/udp)")
+	listCmd.Flags().StringVar(&filterRemote, "remote", "", "Filter by remote host[:port] (implies --all)")
+	listCmd.Flags().StringSliceVar(&filterStates, "state", nil, "Filter by TCP state of owned sockets, e.g. close_wait,established (implies --all)")
+	listCmd.Flags().StringVar(&filterNetNS, "netns", "", "Filter by network namespace ID, PID or name (Linux)")
+	listCmd.Flags().StringVar(&filterCont, "container", "", "Filter by container runtime or ID prefix (Linux)")
+	listCmd.Flags().StringVar(&filterWhere, "where", "", "Filter by an expression, e.g. 'user == me && port >= 3000'")
+	listCmd.Flags().BoolVar(&probeListeners, "probe", false, "Connect to TCP listeners to identify their protocol (HTTP, TLS, SSH, Redis, PostgreSQL, MySQL)")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
+	ctx, cancel := commandContext(context.Background())
+	defer cancel()
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
 
-	var entries []port.PortEntry
-	var err error
+	if err := resolveFilters(ctx); err != nil {
+		return err
+	}
 
-	if listAll {
-		entries, err = scanner.ListAllPorts(ctx)
-	} else {
-		entries, err = scanner.ListPorts(ctx)
+	if listUnix {
+		return runListUnix(ctx, scanner)
 	}
+
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{All: showConnections()})
 	if err != nil {
 		return fmt.Errorf("failed to scan ports: %w", err)
 	}
-
-	entries = filterEntries(entries)
+	entries := filterEntries(snap.Entries)
 
 	// Sort by port number.
 	sort.Slice(entries, func(i, j int) bool {
 		return entries[i].Port < entries[j].Port
 	})
 
+	var probes []*probe.Result
+	if probeListeners {
+		probes, err = probeEntries(ctx, entries)
+		if err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		return printJSON(entries, probes)
+	}
+
+	return printTable(entries, probes)
+}
+
+// runListUnix lists Unix domain sockets. Only listening sockets are shown
+// unless connections were asked for.
+func runListUnix(ctx context.Context, scanner port.Scanner) error {
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{Unix: true})
+	if err != nil {
+		return fmt.Errorf("failed to scan Unix sockets with %s backend: %w", scanBackend, err)
+	}
+
+	entries := snap.Entries
+	if !showConnections() {
+		var listening []port.PortEntry
+		for _, e := range entries {
+			if e.State == port.StateListen {
+				listening = append(listening, e)
+			}
+		}
+		entries = listening
+	}
+	entries = filterEntries(entries)
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
 	if jsonOutput {
-		return printJSON(entries)
+		return printUnixJSON(entries)
+	}
+
+	containers := anyContainer(entries)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := "PATH"
+	if containers {
+		header += "\tCONTAINER"
+	}
+	fmt.Fprintln(w, header+"\tPID\tPROCESS\tUSER\tSTATE")
+	for _, e := range entries {
+		row := e.Path
+		if containers {
+			row += "\t" + containerLabel(e.Container)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
+			row, e.PID, e.Process, e.User, e.State)
+	}
+	return w.Flush()
+}
+
+func printUnixJSON(entries []port.PortEntry) error {
+	type jsonEntry struct {
+		Path      string `json:"path"`
+		Container string `json:"container,omitempty"`
+		Runtime   string `json:"container_runtime,omitempty"`
+		PID       int    `json:"pid"`
+		Process   string `json:"process"`
+		User      string `json:"user"`
+		State     string `json:"state"`
+		FD        string `json:"fd"`
+		Command   string `json:"command"`
+		Backend   string `json:"backend"`
+	}
+
+	out := make([]jsonEntry, len(entries))
+	for i, e := range entries {
+		out[i] = jsonEntry{
+			Path:      e.Path,
+			Container: e.Container.ID,
+			Runtime:   e.Container.Runtime,
+			PID:       e.PID,
+			Process:   e.Process,
+			User:      e.User,
+			State:     string(e.State),
+			FD:        e.FD,
+			Command:   e.Command,
+			Backend:   scanBackend,
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// resolveFilters prepares filters that need parsing or lookups before a
+// scan, such as resolving the --remote host name.
+func resolveFilters(ctx context.Context) error {
+	if filterPort != "" {
+		s, err := port.ParsePortSet(filterPort, services())
+		if err != nil {
+			return fmt.Errorf("invalid --port: %w", err)
+		}
+		portFilter = s
+	}
+
+	if len(filterStates) > 0 {
+		f, err := port.ParseStateFilter(filterStates)
+		if err != nil {
+			return fmt.Errorf("invalid --state: %w", err)
+		}
+		stateFilter = f
+	}
+
+	if filterRemote != "" {
+		f, err := port.ParseRemoteFilter(ctx, filterRemote, net.DefaultResolver.LookupHost)
+		if err != nil {
+			return fmt.Errorf("invalid --remote: %w", err)
+		}
+		remoteFilter = f
 	}
 
-	return printTable(entries)
+	if filterNetNS != "" {
+		ns, err := port.ResolveNetNS(filterNetNS)
+		if err != nil {
+			return fmt.Errorf("invalid --netns: %w", err)
+		}
+		netnsFilter = ns
+	}
+
+	if filterWhere != "" {
+		x, err := query.Parse(filterWhere, query.Env{User: currentUser(), Services: services()})
+		if err != nil {
+			return fmt.Errorf("invalid --where: %w", err)
+		}
+		whereFilter = x
+	}
+	return nil
+}
+
+// currentUser returns the user "me" stands for in --where: the login user
+// given with --host, or else the user running whport.
+func currentUser() string {
+	if login, _, ok := strings.Cut(hostFlag, "@"); ok {
+		return login
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// showConnections reports whether established connections should be
+// scanned, not just listeners.
+func showConnections() bool {
+	return listAll || remoteFilter != nil || (stateFilter != nil && !stateFilter.OnlyListen()) ||
+		whereFilter.References("state", "remote", "remote_addr", "remote_port")
 }
 
 func filterEntries(entries []port.PortEntry) []port.PortEntry {
 	var filtered []port.PortEntry
 	for _, e := range entries {
-		if filterPort > 0 && e.Port != filterPort {
+		if portFilter != nil && !portFilter.Match(e.Port) {
 			continue
 		}
 		if filterProc != "" && !strings.Contains(strings.ToLower(e.Process), strings.ToLower(filterProc)) {
@@ -80,42 +264,171 @@ func filterEntries(entries []port.PortEntry) []port.PortEntry {
 				continue
 			}
 		}
+		if stateFilter != nil && !stateFilter.Match(e.State) {
+			continue
+		}
+		if remoteFilter != nil && !remoteFilter.Match(e) {
+			continue
+		}
+		if netnsFilter != 0 && e.NetNS != netnsFilter {
+			continue
+		}
+		if filterCont != "" && !e.Container.Match(filterCont) {
+			continue
+		}
+		if !whereFilter.Match(e) {
+			continue
+		}
 		filtered = append(filtered, e)
 	}
 	return filtered
 }
 
-func printTable(entries []port.PortEntry) error {
+// printTable prints entries, with a PROTOCOL/APP column when probes, in
+// the same order as entries, is not nil.
+func printTable(entries []port.PortEntry, probes []*probe.Result) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(w, "PORT\tPROTO\tPID\tPROCESS\tUSER\tSTATE")
-	for _, e := range entries {
-		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n",
-			e.Port, e.Protocol, e.PID, e.Process, e.User, e.State)
+	conns := showConnections()
+
+	// The NETNS column is only useful once sockets from more than one
+	// namespace are listed.
+	var names map[uint64]string
+	if multipleNetNS(entries) {
+		names = port.NetNSNames()
+	}
+	containers := anyContainer(entries)
+
+	header := "PORT\tPROTO\tSERVICE\tBIND\tFAMILY"
+	if names != nil {
+		header += "\tNETNS"
+	}
+	if containers {
+		header += "\tCONTAINER"
+	}
+	if conns {
+		header += "\tREMOTE"
+	}
+	header += "\tPID\tPROCESS\tUSER\tSTATE"
+	if probes != nil {
+		header += "\tPROTOCOL/APP"
+	}
+	fmt.Fprintln(w, header)
+
+	for i, e := range entries {
+		row := fmt.Sprintf("%d\t%s\t%s\t%s\t%s", e.Port, e.Protocol, serviceLabel(e), e.Bind(), e.Family)
+		if names != nil {
+			row += "\t" + netnsLabel(e.NetNS, names)
+		}
+		if containers {
+			row += "\t" + containerLabel(e.Container)
+		}
+		if conns {
+			remote := e.Remote()
+			if remote == "" {
+				remote = "-"
+			}
+			row += "\t" + remote
+		}
+		row += fmt.Sprintf("\t%d\t%s\t%s\t%s", e.PID, e.Process, e.User, e.State)
+		if probes != nil {
+			row += "\t" + probes[i].String()
+		}
+		fmt.Fprintln(w, row)
 	}
 	return w.Flush()
 }
 
-func printJSON(entries []port.PortEntry) error {
+// multipleNetNS reports whether entries come from more than one network
+// namespace.
+func multipleNetNS(entries []port.PortEntry) bool {
+	for _, e := range entries {
+		if e.NetNS != entries[0].NetNS {
+			return true
+		}
+	}
+	return false
+}
+
+// anyContainer reports whether any entry belongs to a container, which is
+// when the CONTAINER column is shown.
+func anyContainer(entries []port.PortEntry) bool {
+	for _, e := range entries {
+		if !e.Container.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// containerLabel formats a container as "docker:3f4a1c9e8b7d", or "-".
+func containerLabel(c port.Container) string {
+	if c.IsZero() {
+		return "-"
+	}
+	return c.String()
+}
+
+// netnsLabel names a network namespace by its `ip netns` name, by "host"
+// for whport's own namespace, or else by its ID.
+func netnsLabel(ns uint64, names map[uint64]string) string {
+	if name, ok := names[ns]; ok {
+		return name
+	}
+	if ns == 0 {
+		return "-"
+	}
+	if ns == port.SelfNetNS() {
+		return "host"
+	}
+	return strconv.FormatUint(ns, 10)
+}
+
+func printJSON(entries []port.PortEntry, probes []*probe.Result) error {
 	type jsonEntry struct {
-		Port     int    `json:"port"`
-		Protocol string `json:"protocol"`
-		PID      int    `json:"pid"`
-		Process  string `json:"process"`
-		User     string `json:"user"`
-		State    string `json:"state"`
-		Command  string `json:"command"`
+		Port       int    `json:"port"`
+		Protocol   string `json:"protocol"`
+		Service    string `json:"service,omitempty"`
+		Bind       string `json:"bind"`
+		Family     string `json:"family"`
+		Zone       string `json:"zone,omitempty"`
+		NetNS      uint64 `json:"netns,omitempty"`
+		Container  string `json:"container,omitempty"`
+		Runtime    string `json:"container_runtime,omitempty"`
+		RemoteAddr string `json:"remote_addr,omitempty"`
+		RemotePort int    `json:"remote_port,omitempty"`
+		PID        int    `json:"pid"`
+		Process    string `json:"process"`
+		User       string `json:"user"`
+		State      string `json:"state"`
+		Command    string `json:"command"`
+		Backend    string `json:"backend"`
+
+		Probe *jsonProbe `json:"probe,omitempty"`
 	}
 
 	out := make([]jsonEntry, len(entries))
 	for i, e := range entries {
 		out[i] = jsonEntry{
-			Port:     e.Port,
-			Protocol: string(e.Protocol),
-			PID:      e.PID,
-			Process:  e.Process,
-			User:     e.User,
-			State:    e.State,
-			Command:  e.Command,
+			Port:       e.Port,
+			Protocol:   string(e.Protocol),
+			Service:    services().Name(e.Port, e.Protocol),
+			Bind:       e.LocalAddr,
+			Family:     string(e.Family),
+			Zone:       e.Zone,
+			NetNS:      e.NetNS,
+			Container:  e.Container.ID,
+			Runtime:    e.Container.Runtime,
+			RemoteAddr: e.RemoteAddr,
+			RemotePort: e.RemotePort,
+			PID:        e.PID,
+			Process:    e.Process,
+			User:       e.User,
+			State:      string(e.State),
+			Command:    e.Command,
+			Backend:    scanBackend,
+		}
+		if probes != nil {
+			out[i].Probe = toJSONProbe(probes[i])
 		}
 	}
 
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/probe"
+)
+
+// probeListeners is set by --probe on list and info.
+var probeListeners bool
+
+// probeEntries connects to the TCP listeners among entries to identify
+// their protocols. The results are in the same order as entries.
+func probeEntries(ctx context.Context, entries []port.PortEntry) ([]*probe.Result, error) {
+	if hostFlag != "" {
+		return nil, fmt.Errorf("--probe is not supported with --host")
+	}
+	return probe.Entries(ctx, entries, probe.DefaultTimeout), nil
+}
+
+// jsonProbe is the JSON form of a probe result.
+type jsonProbe struct {
+	Protocol   string `json:"protocol"`
+	App        string `json:"app,omitempty"`
+	Status     string `json:"status,omitempty"`
+	TLSSubject string `json:"tls_subject,omitempty"`
+	TLSExpiry  string `json:"tls_expiry,omitempty"`
+}
+
+func toJSONProbe(r *probe.Result) *jsonProbe {
+	if r == nil {
+		return nil
+	}
+	out := &jsonProbe{
+		Protocol:   r.Protocol,
+		App:        r.App,
+		Status:     r.Status,
+		TLSSubject: r.TLSSubject,
+	}
+	if !r.TLSExpiry.IsZero() {
+		out.TLSExpiry = r.TLSExpiry.Format(time.RFC3339)
+	}
+	return out
+}
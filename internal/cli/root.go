@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lu-zhengda/whport/internal/config"
 	"github.com/lu-zhengda/whport/internal/port"
 	"github.com/lu-zhengda/whport/internal/process"
+	"github.com/lu-zhengda/whport/internal/procwatch"
 	"github.com/lu-zhengda/whport/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -16,16 +21,37 @@ var (
 	version = "dev"
 
 	// Global flags.
-	jsonOutput bool
+	jsonOutput  bool
+	backendFlag string
+	hostFlag    string
+	timeout     time.Duration
+
+	// cfg is loaded from ~/.config/whport/config.yaml before any command runs.
+	cfg = config.Default()
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "whport",
-	Short: "Port & process manager for macOS",
+	Short: "Port & process manager for macOS and Linux",
 	Long: `whport shows what processes are listening on which ports,
 lets you kill them, and provides a live TUI dashboard.
 Launch without subcommands for interactive TUI mode.`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := config.Load("")
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+		if strings.HasPrefix(hostFlag, "-") {
+			return fmt.Errorf("invalid host %q", hostFlag)
+		}
+		// wait has a --timeout of its own, which hides this one.
+		if !cmd.Root().PersistentFlags().Changed("timeout") {
+			timeout = time.Duration(cfg.Timeout) * time.Second
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if shell, _ := cmd.Flags().GetString("generate-completion"); shell != "" {
 			switch shell {
@@ -39,12 +65,23 @@ Launch without subcommands for interactive TUI mode.`,
 				return fmt.Errorf("unsupported shell: %s (use bash, zsh, or fish)", shell)
 			}
 		}
-		runner := &port.RealCmdRunner{}
-		scanner := port.NewLsofScanner(runner)
+		runner := newRunner()
+		ctx, cancel := commandContext(context.Background())
+		scanner, err := newScanner(ctx, runner)
+		cancel()
+		if err != nil {
+			return err
+		}
 		manager := process.NewRealManager(runner)
+		// Local process events say nothing about a remote host.
+		refresh := procwatch.New(procwatch.Options{
+			Interval: time.Duration(cfg.RefreshInterval) * time.Second,
+			Poll:     hostFlag != "",
+		})
+		defer refresh.Stop()
 
-		p := tea.NewProgram(tui.New(scanner, manager, version), tea.WithAltScreen())
-		_, err := p.Run()
+		p := tea.NewProgram(tui.New(scanner, scanBackend, manager, services(), refresh, timeout, version), tea.WithAltScreen())
+		_, err = p.Run()
 		return err
 	},
 }
@@ -60,10 +97,16 @@ func init() {
 	rootCmd.Flags().String("generate-completion", "", "Generate shell completion (bash, zsh, fish)")
 	rootCmd.Flags().MarkHidden("generate-completion")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output in JSON format")
+	rootCmd.PersistentFlags().StringVar(&backendFlag, "backend", "",
+		fmt.Sprintf("Port scanner backend (%s)", strings.Join(port.BackendNames(), ", ")))
+	rootCmd.PersistentFlags().StringVar(&hostFlag, "host", "", "Inspect and kill processes on a remote host over ssh, e.g. user@box")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "Give up on a scan or command after this long (0 disables)")
 
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(killCmd)
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(freeCmd)
+	rootCmd.AddCommand(waitCmd)
 }
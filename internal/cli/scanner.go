@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+// scanBackend is the name of the backend chosen by the last newScanner call.
+var scanBackend string
+
+// newRunner returns the runner for scan and kill commands: one that runs
+// them on the --host machine over ssh, or locally.
+func newRunner() port.CmdRunner {
+	if hostFlag != "" {
+		return port.NewSSHCmdRunner(hostFlag, &port.RealCmdRunner{})
+	}
+	return &port.RealCmdRunner{}
+}
+
+// newScanner creates the port scanner selected by --backend, falling back
+// to the config file and then to auto-detection. For a remote runner the
+// backends are looked for on the remote host.
+func newScanner(ctx context.Context, runner port.CmdRunner) (port.Scanner, error) {
+	name := backendFlag
+	if name == "" {
+		name = cfg.Backend
+	}
+
+	var (
+		scanner port.Scanner
+		backend string
+		err     error
+	)
+	if ssh, ok := runner.(*port.SSHCmdRunner); ok {
+		scanner, backend, err = port.NewRemoteScanner(ctx, name, ssh)
+	} else {
+		scanner, backend, err = port.NewScanner(name, runner)
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanBackend = backend
+	return scanner, nil
+}
+
+// commandContext returns a context derived from parent that is cancelled
+// after --timeout, unless the timeout is disabled.
+func commandContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// takeSnapshot scans with the scanner created by newScanner. Each scan is
+// bounded by --timeout, so a hung lsof cannot hang the command.
+func takeSnapshot(ctx context.Context, scanner port.Scanner, opts port.SnapshotOptions) (*port.Snapshot, error) {
+	ctx, cancel := commandContext(ctx)
+	defer cancel()
+
+	snap, err := port.TakeSnapshot(ctx, scanner, scanBackend, opts)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("scan timed out after %s (see --timeout): %w", timeout, err)
+	}
+	return snap, err
+}
+
+// singleTarget reports whether the command line names one port or Unix
+// socket path, rather than a set of ports.
+func singleTarget(args []string) bool {
+	if len(args) == 1 && port.IsUnixPath(args[0]) {
+		return true
+	}
+	set, err := port.ParsePortSet(strings.Join(args, ","), services())
+	if err != nil {
+		return true
+	}
+	_, ok := set.Single()
+	return ok
+}
+
+// findTarget takes a snapshot of the sockets bound to the ports or Unix
+// socket path given on the command line, and returns it with a description
+// of the target such as "port 3000", "ports 3000-3999" or
+// "/run/docker.sock". Ports may be given as several arguments or as one
+// port set. With no arguments every listener is a target, for commands
+// that select them with --where instead.
+func findTarget(ctx context.Context, scanner port.Scanner, args []string) (*port.Snapshot, string, error) {
+	if len(args) == 1 && port.IsUnixPath(args[0]) {
+		arg := args[0]
+		path := arg
+		if !filepath.IsAbs(path) && path[0] != '@' {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid socket path: %w", err)
+			}
+			path = abs
+		}
+
+		snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{Path: path})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find processes on %s: %w", path, err)
+		}
+		return snap, path, nil
+	}
+
+	for _, arg := range args {
+		if port.IsUnixPath(arg) {
+			return nil, "", fmt.Errorf("socket path %s cannot be combined with other targets", arg)
+		}
+	}
+	var set *port.PortSet
+	where := "any port"
+	if len(args) > 0 {
+		var err error
+		set, err = port.ParsePortSet(strings.Join(args, ","), services())
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid port: %w", err)
+		}
+		where = "ports " + set.String()
+	}
+
+	if portNum, ok := set.Single(); ok {
+		snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{Port: portNum})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find processes on port %d: %w", portNum, err)
+		}
+		return snap, fmt.Sprintf("port %d", portNum), nil
+	}
+
+	// Only listeners are of interest across a set; connections would
+	// match every ephemeral port in a range.
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find processes on %s: %w", where, err)
+	}
+	var entries []port.PortEntry
+	for _, e := range snap.Entries {
+		if set.Match(e.Port) {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Port < entries[j].Port
+	})
+	snap.Entries = entries
+	return snap, where, nil
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"sync"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+var (
+	resolverOnce sync.Once
+	resolver     *port.ServiceResolver
+)
+
+// services returns the service name resolver, with the names set in the
+// config file taking precedence. It is loaded on first use.
+func services() *port.ServiceResolver {
+	resolverOnce.Do(func() {
+		resolver = port.NewServiceResolver(cfg.Services)
+	})
+	return resolver
+}
+
+// serviceLabel names the service on a port, or "-".
+func serviceLabel(e port.PortEntry) string {
+	if name := services().Name(e.Port, e.Protocol); name != "" {
+		return name
+	}
+	return "-"
+}
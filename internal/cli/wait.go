@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/probe"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitUntil    string
+	waitTimeout  time.Duration
+	waitInterval int
+	waitProcess  string
+	waitConnect  bool
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <port>",
+	Short: "Wait until a port is listening or released",
+	Long: `Block until a port reaches a state, then exit 0. Exits 1 if --timeout
+passes first.
+
+  listening  a process listens on the port
+  free       nothing listens on the port (connections may linger)
+  closed     no process holds a socket on the port, listening or connected
+
+Sockets that no process owns, such as those in TIME_WAIT, are not seen, so
+the port may still be in TIME_WAIT once it is closed.
+
+With --process only sockets of a matching process count. With --connect,
+listening also requires a TCP connection to the port to succeed; wait
+fails at once if the listener is in another network namespace, such as a
+container's, since it cannot be connected to from here.
+
+--timeout here is how long to wait for the port. Each scan is still
+bounded by the timeout in the config file, 30 seconds by default.`,
+	Example: `  whport wait 5173 && open http://localhost:5173
+  whport wait 3000 --until listening --process node --connect
+  whport wait 5432 --until free --timeout 10s`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWait,
+}
+
+func init() {
+	waitCmd.Flags().StringVar(&waitUntil, "until", "listening", "State to wait for: listening, free or closed")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 60*time.Second, "Give up and exit 1 after this long (0 waits forever)")
+	waitCmd.Flags().IntVar(&waitInterval, "interval", 1, "Check the port every this many seconds")
+	waitCmd.Flags().StringVar(&waitProcess, "process", "", "Only count sockets of processes whose name contains this")
+	waitCmd.Flags().BoolVar(&waitConnect, "connect", false, "With --until listening, also require a TCP connection to succeed")
+}
+
+func runWait(cmd *cobra.Command, args []string) error {
+	portNum, err := services().ParsePort(args[0])
+	if err != nil {
+		return err
+	}
+	opts := port.WaitOptions{
+		Until:       port.WaitState(strings.ToLower(waitUntil)),
+		Process:     waitProcess,
+		Interval:    time.Duration(waitInterval) * time.Second,
+		ScanTimeout: timeout,
+	}
+	switch opts.Until {
+	case port.WaitListening, port.WaitFree, port.WaitClosed:
+	default:
+		return fmt.Errorf("invalid --until %q (use listening, free or closed)", waitUntil)
+	}
+	if waitConnect {
+		if opts.Until != port.WaitListening {
+			return fmt.Errorf("--connect only applies to --until listening")
+		}
+		if hostFlag != "" {
+			return fmt.Errorf("--connect is not supported with --host")
+		}
+		opts.Connect = canConnect
+	}
+	if waitInterval < 1 {
+		return fmt.Errorf("invalid --interval %d: must be at least 1", waitInterval)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if waitTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	listener, err := port.WaitForPort(ctx, scanner, portNum, opts)
+	if err != nil && ctx.Err() != nil {
+		msg := fmt.Sprintf("stopped waiting for port %d to be %s", portNum, opts.Until)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			msg = fmt.Sprintf("timed out after %s waiting for port %d to be %s", waitTimeout, portNum, opts.Until)
+		}
+		var scanErr *port.ScanError
+		if errors.As(err, &scanErr) {
+			return fmt.Errorf("%s; the last scan failed: %w", msg, scanErr.Err)
+		}
+		return errors.New(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check port %d: %w", portNum, err)
+	}
+	return printWaitResult(portNum, string(opts.Until), time.Since(start), listener)
+}
+
+// canConnect reports whether a TCP connection to a listener succeeds. It
+// fails for listeners in other network namespaces, which cannot be
+// connected to from here. Other sockets, such as UDP ones, never count.
+func canConnect(ctx context.Context, e port.PortEntry) (bool, error) {
+	if e.Protocol != port.TCP {
+		return false, nil
+	}
+	addr, ok := probe.Target(e)
+	if !ok {
+		return false, fmt.Errorf("--connect cannot reach %s (PID %d) on port %d in network namespace %d", e.Process, e.PID, e.Port, e.NetNS)
+	}
+	d := net.Dialer{Timeout: time.Second}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false, nil
+	}
+	conn.Close()
+	return true, nil
+}
+
+func printWaitResult(portNum int, until string, waited time.Duration, e *port.PortEntry) error {
+	if jsonOutput {
+		type jsonWait struct {
+			Port    int     `json:"port"`
+			State   string  `json:"state"`
+			Waited  float64 `json:"waited_seconds"`
+			PID     int     `json:"pid,omitempty"`
+			Process string  `json:"process,omitempty"`
+		}
+		out := jsonWait{Port: portNum, State: until, Waited: waited.Seconds()}
+		if e != nil {
+			out.PID = e.PID
+			out.Process = e.Process
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	if e != nil {
+		fmt.Printf("Port %d is %s: %s (PID %d), after %s.\n", portNum, until, e.Process, e.PID, waited.Round(time.Millisecond))
+	} else {
+		fmt.Printf("Port %d is %s, after %s.\n", portNum, until, waited.Round(time.Millisecond))
+	}
+	return nil
+}
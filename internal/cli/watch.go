@@ -12,12 +12,14 @@ import (
 	"time"
 
 	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/procwatch"
 	"github.com/spf13/cobra"
 )
 
 var (
 	watchInterval int
 	watchAlert    bool
+	watchPoll     bool
 )
 
 var watchCmd = &cobra.Command{
@@ -25,6 +27,9 @@ var watchCmd = &cobra.Command{
 	Short: "Auto-refresh port table in terminal",
 	Long: `Continuously display listening ports with periodic refresh.
 
+The table is refreshed every --interval seconds. On Linux, when run as root,
+it is also refreshed as soon as processes start or exit.
+
 With --alert, monitors for new port listeners that appear after the initial
 scan. When a new listener is detected, prints an alert and exits with code 1.
 Useful for security monitoring.`,
@@ -33,9 +38,14 @@ Useful for security monitoring.`,
 
 func init() {
 	watchCmd.Flags().IntVar(&watchInterval, "interval", 2, "Refresh interval in seconds")
-	watchCmd.Flags().IntVar(&filterPort, "port", 0, "Filter by port number")
+	watchCmd.Flags().BoolVar(&watchPoll, "poll", false, "Only refresh every --interval seconds, ignoring process events")
+	watchCmd.Flags().StringVar(&filterPort, "port", "", "Filter by ports, ranges and service names, e.g. 3000-3999,postgres,!5432")
 	watchCmd.Flags().StringVar(&filterProc, "process", "", "Filter by process name")
 	watchCmd.Flags().StringVar(&filterProto, "protocol", "", "Filter by protocol (tcp/udp)")
+	watchCmd.Flags().StringVar(&filterRemote, "remote", "", "Filter by remote host[:port] (implies all connections)")
+	watchCmd.Flags().StringSliceVar(&filterStates, "state", nil, "Filter by TCP state of owned sockets, e.g. close_wait,established (implies all connections)")
+	watchCmd.Flags().StringVar(&filterCont, "container", "", "Filter by container runtime or ID prefix (Linux)")
+	watchCmd.Flags().StringVar(&filterWhere, "where", "", "Filter by an expression, e.g. 'user == me && port >= 3000'")
 	watchCmd.Flags().BoolVar(&watchAlert, "alert", false, "Alert and exit on new port listeners")
 }
 
@@ -47,12 +57,16 @@ func runWatch(cmd *cobra.Command, args []string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
-	interval := time.Duration(watchInterval) * time.Second
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
+	if err := resolveFilters(ctx); err != nil {
+		return err
+	}
+	trigger := newRefreshTrigger()
+	defer trigger.Stop()
 
 	// Initial scan.
 	if err := watchOnce(ctx, scanner); err != nil {
@@ -64,7 +78,7 @@ func runWatch(cmd *cobra.Command, args []string) error {
 		case <-ctx.Done():
 			fmt.Println("\nStopped watching.")
 			return nil
-		case <-ticker.C:
+		case <-trigger.C:
 			if err := watchOnce(ctx, scanner); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			}
@@ -76,10 +90,14 @@ func runWatchAlert(cmd *cobra.Command, args []string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	runner := &port.RealCmdRunner{}
-	scanner := port.NewLsofScanner(runner)
-	interval := time.Duration(watchInterval) * time.Second
-
+	runner := newRunner()
+	scanner, err := newScanner(ctx, runner)
+	if err != nil {
+		return err
+	}
+	if err := resolveFilters(ctx); err != nil {
+		return err
+	}
 	// Baseline scan.
 	baseline, err := scanFiltered(ctx, scanner)
 	if err != nil {
@@ -88,14 +106,17 @@ func runWatchAlert(cmd *cobra.Command, args []string) error {
 
 	baselineKeys := makePortKeySet(baseline)
 
+	trigger := newRefreshTrigger()
+	defer trigger.Stop()
+
 	if !jsonOutput {
-		fmt.Printf("Monitoring %d port(s) for new listeners... (interval: %ds)\n",
-			len(baseline), watchInterval)
+		refresh := fmt.Sprintf("interval: %ds", watchInterval)
+		if trigger.Events() {
+			refresh += " and on process events"
+		}
+		fmt.Printf("Monitoring %d port(s) for new listeners... (%s)\n", len(baseline), refresh)
 	}
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -103,7 +124,7 @@ func runWatchAlert(cmd *cobra.Command, args []string) error {
 				fmt.Println("\nStopped watching.")
 			}
 			return nil
-		case <-ticker.C:
+		case <-trigger.C:
 			current, err := scanFiltered(ctx, scanner)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -121,21 +142,26 @@ func runWatchAlert(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// newRefreshTrigger returns the trigger that paces watch's rescans.
+func newRefreshTrigger() *procwatch.Trigger {
+	interval := time.Duration(watchInterval) * time.Second
+	return procwatch.New(procwatch.Options{
+		Interval: interval,
+		// Events add rescans; a running process opening a new listener
+		// still has to be found within the interval.
+		SafetyPoll: interval,
+		// Local process events say nothing about a remote host.
+		Poll: watchPoll || hostFlag != "",
+	})
+}
+
 // scanFiltered performs a port scan and applies the current filters.
-func scanFiltered(ctx context.Context, scanner *port.LsofScanner) ([]port.PortEntry, error) {
-	var entries []port.PortEntry
-	var err error
-
-	if listAll {
-		entries, err = scanner.ListAllPorts(ctx)
-	} else {
-		entries, err = scanner.ListPorts(ctx)
-	}
+func scanFiltered(ctx context.Context, scanner port.Scanner) ([]port.PortEntry, error) {
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{All: showConnections()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan ports: %w", err)
 	}
-
-	return filterEntries(entries), nil
+	return filterEntries(snap.Entries), nil
 }
 
 // portKeyStr creates a unique key for identifying a port listener.
@@ -175,13 +201,18 @@ func (e *alertExitError) Error() string {
 
 func printAlertJSON(entries []port.PortEntry) error {
 	type alertEntry struct {
-		Port     int    `json:"port"`
-		Protocol string `json:"protocol"`
-		PID      int    `json:"pid"`
-		Process  string `json:"process"`
-		User     string `json:"user"`
-		State    string `json:"state"`
-		Command  string `json:"command"`
+		Port       int    `json:"port"`
+		Protocol   string `json:"protocol"`
+		Bind       string `json:"bind"`
+		Family     string `json:"family"`
+		Zone       string `json:"zone,omitempty"`
+		RemoteAddr string `json:"remote_addr,omitempty"`
+		RemotePort int    `json:"remote_port,omitempty"`
+		PID        int    `json:"pid"`
+		Process    string `json:"process"`
+		User       string `json:"user"`
+		State      string `json:"state"`
+		Command    string `json:"command"`
 	}
 
 	type alertOutput struct {
@@ -197,13 +228,18 @@ func printAlertJSON(entries []port.PortEntry) error {
 	}
 	for i, e := range entries {
 		out.Entries[i] = alertEntry{
-			Port:     e.Port,
-			Protocol: string(e.Protocol),
-			PID:      e.PID,
-			Process:  e.Process,
-			User:     e.User,
-			State:    e.State,
-			Command:  e.Command,
+			Port:       e.Port,
+			Protocol:   string(e.Protocol),
+			Bind:       e.LocalAddr,
+			Family:     string(e.Family),
+			Zone:       e.Zone,
+			RemoteAddr: e.RemoteAddr,
+			RemotePort: e.RemotePort,
+			PID:        e.PID,
+			Process:    e.Process,
+			User:       e.User,
+			State:      string(e.State),
+			Command:    e.Command,
 		}
 	}
 
@@ -220,30 +256,22 @@ func printAlertHuman(entries []port.PortEntry) error {
 	fmt.Printf("\nALERT: %d new port listener(s) detected!\n\n", len(entries))
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(w, "PORT\tPROTO\tPID\tPROCESS\tUSER\tSTATE")
+	fmt.Fprintln(w, "PORT\tPROTO\tBIND\tPID\tPROCESS\tUSER\tSTATE")
 	for _, e := range entries {
-		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n",
-			e.Port, e.Protocol, e.PID, e.Process, e.User, e.State)
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.Port, e.Protocol, e.Bind(), e.PID, e.Process, e.User, e.State)
 	}
 	w.Flush()
 
 	return &alertExitError{count: len(entries)}
 }
 
-func watchOnce(ctx context.Context, scanner *port.LsofScanner) error {
-	var entries []port.PortEntry
-	var err error
-
-	if listAll {
-		entries, err = scanner.ListAllPorts(ctx)
-	} else {
-		entries, err = scanner.ListPorts(ctx)
-	}
+func watchOnce(ctx context.Context, scanner port.Scanner) error {
+	snap, err := takeSnapshot(ctx, scanner, port.SnapshotOptions{All: showConnections()})
 	if err != nil {
 		return fmt.Errorf("failed to scan ports: %w", err)
 	}
-
-	entries = filterEntries(entries)
+	entries := filterEntries(snap.Entries)
 
 	sort.Slice(entries, func(i, j int) bool {
 		return entries[i].Port < entries[j].Port
@@ -255,12 +283,12 @@ func watchOnce(ctx context.Context, scanner *port.LsofScanner) error {
 	// Header.
 	listenCount := 0
 	for _, e := range entries {
-		if e.State == "LISTEN" {
+		if e.State == port.StateListen {
 			listenCount++
 		}
 	}
 	fmt.Printf("whport watch | Listening: %d  Total: %d | %s | Ctrl+C to stop\n\n",
-		listenCount, len(entries), time.Now().Format("15:04:05"))
+		listenCount, len(entries), snap.Time.Format("15:04:05"))
 
 	if len(entries) == 0 {
 		fmt.Println("No ports found matching filter.")
@@ -268,14 +296,18 @@ func watchOnce(ctx context.Context, scanner *port.LsofScanner) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-	fmt.Fprintln(w, "PORT\tPROTO\tPID\tPROCESS\tUSER\tSTATE\tCOMMAND")
+	fmt.Fprintln(w, "PORT\tPROTO\tSERVICE\tBIND\tREMOTE\tPID\tPROCESS\tUSER\tSTATE\tCOMMAND")
 	for _, e := range entries {
 		cmd := e.Command
 		if len(cmd) > 40 {
 			cmd = cmd[:37] + "..."
 		}
-		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\t%s\n",
-			e.Port, e.Protocol, e.PID, e.Process, e.User, e.State, cmd)
+		remote := e.Remote()
+		if remote == "" {
+			remote = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			e.Port, e.Protocol, serviceLabel(e), e.Bind(), remote, e.PID, e.Process, e.User, e.State, cmd)
 	}
 	w.Flush()
 
@@ -288,8 +320,8 @@ func watchOnce(ctx context.Context, scanner *port.LsofScanner) error {
 
 func activeFilter() string {
 	var parts []string
-	if filterPort > 0 {
-		parts = append(parts, fmt.Sprintf("port=%d", filterPort))
+	if filterPort != "" {
+		parts = append(parts, fmt.Sprintf("port=%s", filterPort))
 	}
 	if filterProc != "" {
 		parts = append(parts, fmt.Sprintf("process=%s", filterProc))
@@ -297,5 +329,17 @@ func activeFilter() string {
 	if filterProto != "" {
 		parts = append(parts, fmt.Sprintf("protocol=%s", filterProto))
 	}
+	if stateFilter != nil {
+		parts = append(parts, fmt.Sprintf("state=%s", stateFilter))
+	}
+	if filterRemote != "" {
+		parts = append(parts, fmt.Sprintf("remote=%s", filterRemote))
+	}
+	if filterCont != "" {
+		parts = append(parts, fmt.Sprintf("container=%s", filterCont))
+	}
+	if filterWhere != "" {
+		parts = append(parts, fmt.Sprintf("where=%s", filterWhere))
+	}
 	return strings.Join(parts, ", ")
 }
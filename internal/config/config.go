@@ -15,6 +15,11 @@ type Config struct {
 	KillSignal      string   `yaml:"kill_signal"`      // default signal name
 	Exclude         []string `yaml:"exclude"`          // process names to hide
 	ColorEnabled    bool     `yaml:"color_enabled"`
+	Backend         string   `yaml:"backend"` // scanner backend, or "auto"
+	Timeout         int      `yaml:"timeout"` // seconds per scan or command, 0 disables
+
+	// Services names ports, overriding /etc/services, e.g. 8000: api.
+	Services map[int]string `yaml:"services"`
 }
 
 // Default returns a Config with sensible default values.
@@ -25,6 +30,8 @@ func Default() *Config {
 		KillSignal:      "SIGTERM",
 		Exclude:         []string{},
 		ColorEnabled:    true,
+		Backend:         "auto",
+		Timeout:         30,
 	}
 }
 
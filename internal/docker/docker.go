@@ -0,0 +1,264 @@
+// Package docker maps host ports published by Docker to their containers
+// through the Docker Engine API.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// apiVersion is the Engine API version requested. 1.41 is Docker 20.10,
+// old enough for every supported daemon and new enough for the fields used.
+const apiVersion = "v1.41"
+
+// requestTimeout bounds API calls so an unresponsive daemon cannot hang
+// info or kill.
+const requestTimeout = 5 * time.Second
+
+// Compose labels set on containers started by docker compose.
+const (
+	labelComposeProject = "com.docker.compose.project"
+	labelComposeService = "com.docker.compose.service"
+)
+
+// proxyProcesses lists the processes that hold published ports on behalf
+// of containers: docker-proxy on Linux, the Docker Desktop backend on
+// macOS, and rootlesskit for rootless Docker.
+var proxyProcesses = []string{
+	"docker-proxy",
+	"com.docker.backend",
+	"com.docker.vpnkit",
+	"vpnkit-bridge",
+	"rootlesskit",
+}
+
+// IsProxy reports whether a process name belongs to a Docker port proxy.
+func IsProxy(process string) bool {
+	for _, p := range proxyProcesses {
+		if strings.EqualFold(process, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Published describes a host port published by a container.
+type Published struct {
+	ContainerID   string
+	Name          string // container name without the leading "/"
+	Image         string
+	Project       string // compose project, if any
+	Service       string // compose service, if any
+	HostIP        string
+	HostPort      int
+	ContainerPort int
+	Protocol      string // "tcp" or "udp"
+}
+
+// String returns e.g. "web (nginx:1.27) 8080->80/tcp".
+func (p Published) String() string {
+	return fmt.Sprintf("%s (%s) %d->%d/%s", p.Name, p.Image, p.HostPort, p.ContainerPort, p.Protocol)
+}
+
+// Client talks to the Docker Engine API over a Unix socket.
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient creates a client for the daemon listening on socket. An empty
+// socket selects the daemon from DOCKER_HOST, falling back to the default
+// socket locations of Docker Engine and Docker Desktop.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		socket = defaultSocket()
+	}
+	return &Client{
+		socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// Socket returns the path of the daemon socket the client uses.
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// defaultSocket returns the Unix socket named by DOCKER_HOST, or the first
+// well-known socket that exists.
+func defaultSocket() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if path, ok := strings.CutPrefix(host, "unix://"); ok {
+			return path
+		}
+	}
+
+	candidates := []string{"/var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "docker.sock"))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return candidates[0]
+}
+
+// apiContainer is the subset of a /containers/json entry that is used.
+type apiContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// PublishedPorts lists every host port published by a running container.
+func (c *Client) PublishedPorts(ctx context.Context) ([]Published, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var containers []apiContainer
+	if err := c.do(ctx, http.MethodGet, "/containers/json", &containers); err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var published []Published
+	for _, ct := range containers {
+		name := ct.ID
+		if len(ct.Names) > 0 {
+			name = strings.TrimPrefix(ct.Names[0], "/")
+		}
+		for _, p := range ct.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			published = append(published, Published{
+				ContainerID:   ct.ID,
+				Name:          name,
+				Image:         ct.Image,
+				Project:       ct.Labels[labelComposeProject],
+				Service:       ct.Labels[labelComposeService],
+				HostIP:        p.IP,
+				HostPort:      p.PublicPort,
+				ContainerPort: p.PrivatePort,
+				Protocol:      p.Type,
+			})
+		}
+	}
+	return published, nil
+}
+
+// FindPublished returns the container publishing hostPort over proto
+// ("tcp" or "udp"), or nil if no container publishes it. When the port is
+// published on several addresses, hostIP picks the one bound to it; the
+// wildcard may be given as "*", "0.0.0.0", "::" or "".
+func (c *Client) FindPublished(ctx context.Context, hostPort int, proto, hostIP string) (*Published, error) {
+	published, err := c.PublishedPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *Published
+	for i, p := range published {
+		if p.HostPort != hostPort || !strings.EqualFold(p.Protocol, proto) {
+			continue
+		}
+		if bindHost(p.HostIP) == bindHost(hostIP) {
+			return &published[i], nil
+		}
+		if match == nil {
+			match = &published[i]
+		}
+	}
+	return match, nil
+}
+
+// bindHost returns "*" for the spellings of the wildcard address used by
+// the Engine API and by whport, and other addresses unchanged.
+func bindHost(ip string) string {
+	switch ip {
+	case "", "*", "0.0.0.0", "::", "[::]":
+		return "*"
+	}
+	return ip
+}
+
+// Stop stops a container, letting it shut down for up to timeout before
+// the daemon kills it.
+func (c *Client) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	q := url.Values{"t": {fmt.Sprint(int(timeout.Seconds()))}}
+	path := "/containers/" + url.PathEscape(id) + "/stop?" + q.Encode()
+
+	// The daemon only answers once the container has stopped.
+	ctx, cancel := context.WithTimeout(ctx, timeout+requestTimeout)
+	defer cancel()
+
+	if err := c.do(ctx, http.MethodPost, path, nil); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", id, err)
+	}
+	return nil
+}
+
+// do sends a request without a body and decodes the JSON response into
+// out, unless out is nil.
+func (c *Client) do(ctx context.Context, method, path string, out any) error {
+	// The host is ignored since the transport always dials the socket.
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker/"+apiVersion+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkStatus turns an error response into an error carrying the daemon's
+// message. 304 Not Modified, as returned when stopping a stopped
+// container, is not an error.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Message != "" {
+		return fmt.Errorf("docker API: %s", apiErr.Message)
+	}
+	return fmt.Errorf("docker API: %s", resp.Status)
+}
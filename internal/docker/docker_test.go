@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const containersFixture = `[
+  {
+    "Id": "3f4a1c9e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f",
+    "Names": ["/shop-web-1"],
+    "Image": "nginx:1.27",
+    "Labels": {
+      "com.docker.compose.project": "shop",
+      "com.docker.compose.service": "web"
+    },
+    "Ports": [
+      {"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+      {"IP": "::", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+      {"PrivatePort": 443, "Type": "tcp"}
+    ]
+  },
+  {
+    "Id": "9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b",
+    "Names": ["/dns"],
+    "Image": "coredns/coredns",
+    "Labels": {},
+    "Ports": [
+      {"IP": "127.0.0.1", "PrivatePort": 53, "PublicPort": 5353, "Type": "udp"},
+      {"IP": "127.0.0.2", "PrivatePort": 53, "PublicPort": 8080, "Type": "tcp"}
+    ]
+  }
+]`
+
+// fakeDaemon serves a minimal Docker Engine API on a Unix socket and
+// records the requests it receives.
+type fakeDaemon struct {
+	mu         sync.Mutex
+	requests   []string
+	containers string // served instead of containersFixture, if set
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	d.requests = append(d.requests, r.Method+" "+r.URL.RequestURI())
+	containers := d.containers
+	d.mu.Unlock()
+	if containers == "" {
+		containers = containersFixture
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/"+apiVersion+"/containers/json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(containers))
+	case r.Method == http.MethodPost && r.URL.Path == "/"+apiVersion+"/containers/3f4a1c9e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f/stop":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: missing"}`))
+	}
+}
+
+func startFakeDaemon(t *testing.T) (*Client, *fakeDaemon) {
+	t.Helper()
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir
+	// can exceed on macOS.
+	dir, err := os.MkdirTemp("", "whport-docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDaemon{}
+	srv := &http.Server{Handler: d}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	return NewClient(socket), d
+}
+
+func TestPublishedPorts(t *testing.T) {
+	c, _ := startFakeDaemon(t)
+
+	published, err := c.PublishedPorts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(published) != 4 {
+		t.Fatalf("expected 4 published ports, got %d: %v", len(published), published)
+	}
+
+	p := published[0]
+	if p.Name != "shop-web-1" || p.Image != "nginx:1.27" {
+		t.Errorf("container: got %s (%s)", p.Name, p.Image)
+	}
+	if p.Project != "shop" || p.Service != "web" {
+		t.Errorf("compose: got %s/%s, want shop/web", p.Project, p.Service)
+	}
+	if p.HostPort != 8080 || p.ContainerPort != 80 || p.Protocol != "tcp" {
+		t.Errorf("mapping: got %d->%d/%s", p.HostPort, p.ContainerPort, p.Protocol)
+	}
+	if got := p.String(); got != "shop-web-1 (nginx:1.27) 8080->80/tcp" {
+		t.Errorf("String: got %q", got)
+	}
+}
+
+func TestFindPublished(t *testing.T) {
+	c, _ := startFakeDaemon(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		port    int
+		proto   string
+		hostIP  string
+		wantNil bool
+		name    string
+	}{
+		{8080, "TCP", "*", false, "shop-web-1"},
+		{8080, "tcp", "127.0.0.2", false, "dns"},
+		{5353, "UDP", "127.0.0.1", false, "dns"},
+		{5353, "TCP", "127.0.0.1", true, ""},
+		{9999, "TCP", "*", true, ""},
+	}
+
+	for _, tt := range tests {
+		p, err := c.FindPublished(ctx, tt.port, tt.proto, tt.hostIP)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tt.wantNil {
+			if p != nil {
+				t.Errorf("%d/%s: expected no match, got %v", tt.port, tt.proto, p)
+			}
+			continue
+		}
+		if p == nil || p.Name != tt.name {
+			t.Errorf("%d/%s on %s: got %v, want %s", tt.port, tt.proto, tt.hostIP, p, tt.name)
+		}
+	}
+}
+
+func TestFindPublished_BindAddress(t *testing.T) {
+	c, d := startFakeDaemon(t)
+	d.mu.Lock()
+	d.containers = `[
+  {"Id": "a1", "Names": ["/api"], "Image": "api", "Ports": [
+    {"IP": "127.0.0.1", "PrivatePort": 3000, "PublicPort": 3000, "Type": "tcp"}
+  ]},
+  {"Id": "b2", "Names": ["/web"], "Image": "web", "Ports": [
+    {"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 3000, "Type": "tcp"},
+    {"IP": "::", "PrivatePort": 80, "PublicPort": 3000, "Type": "tcp"}
+  ]}
+]`
+	d.mu.Unlock()
+
+	tests := []struct {
+		hostIP string
+		want   string
+	}{
+		{"127.0.0.1", "api"},
+		{"*", "web"},
+		{"0.0.0.0", "web"},
+		{"::", "web"},
+		{"", "web"},
+		{"10.0.0.1", "api"}, // no exact match: the first binding of the port
+	}
+	for _, tt := range tests {
+		p, err := c.FindPublished(context.Background(), 3000, "tcp", tt.hostIP)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p == nil || p.Name != tt.want {
+			t.Errorf("%q: got %v, want %s", tt.hostIP, p, tt.want)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	c, d := startFakeDaemon(t)
+	ctx := context.Background()
+
+	id := "3f4a1c9e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f"
+	if err := c.Stop(ctx, id, 10*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "POST /" + apiVersion + "/containers/" + id + "/stop?t=10"
+	if len(d.requests) != 1 || d.requests[0] != want {
+		t.Errorf("requests: got %v, want [%s]", d.requests, want)
+	}
+
+	err := c.Stop(ctx, "missing", time.Second)
+	if err == nil {
+		t.Fatal("expected error for unknown container")
+	}
+	if got := err.Error(); got != "failed to stop container missing: docker API: No such container: missing" {
+		t.Errorf("error: got %q", got)
+	}
+}
+
+func TestClient_DaemonDown(t *testing.T) {
+	c := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+	if _, err := c.PublishedPorts(context.Background()); err == nil {
+		t.Fatal("expected error when the daemon is not running")
+	}
+}
+
+func TestIsProxy(t *testing.T) {
+	for name, want := range map[string]bool{
+		"docker-proxy":       true,
+		"com.docker.backend": true,
+		"rootlesskit":        true,
+		"dockerd":            false,
+		"nginx":              false,
+	} {
+		if got := IsProxy(name); got != want {
+			t.Errorf("IsProxy(%q): got %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package port
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// endpoint is one side of a socket address as printed by lsof, ss or
+// netstat, e.g. "127.0.0.1:5432", "*:80", "[::1]:5432" or
+// "[fe80::1%en0]:123".
+type endpoint struct {
+	addr string
+	zone string
+	port int // -1 for a wildcard port
+}
+
+// parseEndpoint splits an address:port string. Accepted forms include
+// "host:port", "[v6]:port", "[v6%zone]:port", "[v6]%zone:port",
+// "v4%iface:port" and netstat's unbracketed ":::22". A "*" port is
+// returned as -1.
+func parseEndpoint(s string) (endpoint, bool) {
+	idx := strings.LastIndex(s, ":")
+	if idx == -1 {
+		return endpoint{}, false
+	}
+	host, portStr := s[:idx], s[idx+1:]
+
+	ep := endpoint{port: -1}
+	if portStr != "*" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 0 || port > 65535 {
+			return endpoint{}, false
+		}
+		ep.port = port
+	}
+
+	if strings.HasPrefix(host, "[") {
+		end := strings.Index(host, "]")
+		if end == -1 {
+			return endpoint{}, false
+		}
+		if zone, ok := strings.CutPrefix(host[end+1:], "%"); ok {
+			ep.zone = zone
+		}
+		host = host[1:end]
+	}
+	if i := strings.Index(host, "%"); i != -1 {
+		ep.zone = host[i+1:]
+		host = host[:i]
+	}
+
+	ep.addr = host
+	return ep, true
+}
+
+// isWildcardAddr reports whether addr is one of the spellings of "any
+// address" used by the backends.
+func isWildcardAddr(addr string) bool {
+	switch addr {
+	case "*", "", "0.0.0.0", "::", "[::]":
+		return true
+	}
+	return false
+}
+
+// v6OnlyState is what a backend knows of a socket's IPV6_V6ONLY option.
+type v6OnlyState int
+
+const (
+	v6OnlyUnknown v6OnlyState = iota
+	v6OnlyOff
+	v6OnlyOn
+)
+
+// bindAddr normalizes a local address and works out the family the socket
+// accepts connections on. Wildcards are reported as "*".
+//
+// sockFamily is the family the socket was opened with, or "" when the
+// backend does not say; in that case the address spelling decides, with
+// a bare "*" meaning dual-stack (as ss prints it). IPv6 sockets bound to
+// the wildcard also accept IPv4 connections unless they are v6-only; they
+// are only reported as dual-stack when the backend knows they are not.
+func bindAddr(addr string, sockFamily Family, v6only v6OnlyState) (string, Family) {
+	if isWildcardAddr(addr) {
+		switch sockFamily {
+		case IPv4:
+			return "*", IPv4
+		case IPv6:
+			if v6only == v6OnlyOff {
+				return "*", DualStack
+			}
+			return "*", IPv6
+		}
+		switch addr {
+		case "0.0.0.0":
+			return "*", IPv4
+		case "::", "[::]":
+			return "*", IPv6
+		}
+		return "*", DualStack
+	}
+
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil && !strings.Contains(addr, ":") {
+		return addr, IPv4
+	}
+	if strings.Contains(addr, ":") {
+		return addr, IPv6
+	}
+	return addr, sockFamily
+}
+
+// peerAddr normalizes the remote side of a socket. Unconnected sockets
+// report a wildcard address or a zero port; both are returned as "", 0.
+func peerAddr(addr string, port int) (string, int) {
+	if isWildcardAddr(addr) || port <= 0 {
+		return "", 0
+	}
+	return addr, port
+}
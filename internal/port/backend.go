@@ -0,0 +1,180 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// Auto is the backend name that selects the first available backend.
+const Auto = "auto"
+
+// Backend describes a Scanner implementation that can be selected by name.
+type Backend struct {
+	Name        string
+	Description string
+	// Command is the executable the backend runs, or "" for backends that
+	// read the local kernel and so cannot inspect a remote host.
+	Command string
+	// LinuxOnly is set for backends that parse Linux-specific output.
+	LinuxOnly bool
+	// Available reports whether the backend can run on this host.
+	Available func() bool
+	// New creates a scanner for this backend.
+	New func(runner CmdRunner) Scanner
+}
+
+// backends lists every backend in auto-detection order. Native Linux
+// sources come first since they do not fork a subprocess per scan.
+var backends = []Backend{
+	{
+		Name:        "netlink",
+		Description: "Linux NETLINK_SOCK_DIAG",
+		Available:   NetlinkAvailable,
+		New:         func(CmdRunner) Scanner { return NewNetlinkScanner() },
+	},
+	{
+		Name:        "proc",
+		Description: "Linux /proc/net",
+		Available:   procAvailable,
+		New:         func(CmdRunner) Scanner { return NewProcScanner("") },
+	},
+	{
+		Name:        "ss",
+		Description: "iproute2 ss",
+		Command:     "ss",
+		LinuxOnly:   true,
+		Available:   func() bool { return runtime.GOOS == "linux" && commandAvailable("ss") },
+		New:         func(r CmdRunner) Scanner { return NewSsScanner(r) },
+	},
+	{
+		Name:        "lsof",
+		Description: "lsof -i",
+		Command:     "lsof",
+		Available:   func() bool { return commandAvailable("lsof") },
+		New:         func(r CmdRunner) Scanner { return NewLsofScanner(r) },
+	},
+	{
+		Name:        "netstat",
+		Description: "net-tools netstat -p (Linux)",
+		Command:     "netstat",
+		LinuxOnly:   true,
+		Available:   func() bool { return runtime.GOOS == "linux" && commandAvailable("netstat") },
+		New:         func(r CmdRunner) Scanner { return NewNetstatScanner(r) },
+	},
+}
+
+// Backends returns all registered backends in auto-detection order.
+func Backends() []Backend {
+	return append([]Backend(nil), backends...)
+}
+
+// BackendNames returns the names accepted by NewScanner, including Auto.
+func BackendNames() []string {
+	names := []string{Auto}
+	for _, b := range backends {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// NewScanner creates a scanner for the named backend and returns the name
+// of the backend that was used. An empty name or Auto picks the first
+// backend that is available on this host.
+func NewScanner(name string, runner CmdRunner) (Scanner, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == Auto {
+		for _, b := range backends {
+			if b.Available() {
+				return b.New(runner), b.Name, nil
+			}
+		}
+		return nil, "", fmt.Errorf("no port scanner backend available (tried %s)",
+			strings.Join(BackendNames()[1:], ", "))
+	}
+
+	for _, b := range backends {
+		if b.Name != name {
+			continue
+		}
+		if !b.Available() {
+			return nil, "", fmt.Errorf("backend %q is not available on this host", name)
+		}
+		return b.New(runner), b.Name, nil
+	}
+	return nil, "", fmt.Errorf("unknown backend %q (valid: %s)", name, strings.Join(BackendNames(), ", "))
+}
+
+// NewRemoteScanner creates a scanner for the named backend on the host
+// that runner runs commands on, and returns the name of the backend that
+// was used. Only backends that run a command can scan a remote host; an
+// empty name or Auto picks the first of them that the host has.
+func NewRemoteScanner(ctx context.Context, name string, runner *SSHCmdRunner) (Scanner, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var remote []Backend
+	for _, b := range backends {
+		if b.Command != "" && (name == "" || name == Auto || b.Name == name) {
+			remote = append(remote, b)
+		}
+	}
+	if len(remote) == 0 {
+		if name == "netlink" || name == "proc" {
+			return nil, "", fmt.Errorf("backend %q cannot scan a remote host", name)
+		}
+		return nil, "", fmt.Errorf("unknown backend %q (valid: %s)", name, strings.Join(BackendNames(), ", "))
+	}
+
+	goos, commands, err := probeRemote(ctx, runner, remote)
+	if err != nil {
+		return nil, "", err
+	}
+	var tried []string
+	for _, b := range remote {
+		if commands[b.Command] && (!b.LinuxOnly || goos == "Linux") {
+			return b.New(runner), b.Name, nil
+		}
+		tried = append(tried, b.Name)
+	}
+	if len(remote) == 1 {
+		return nil, "", fmt.Errorf("backend %q is not available on %s", name, runner.Host())
+	}
+	return nil, "", fmt.Errorf("no port scanner backend available on %s (tried %s)",
+		runner.Host(), strings.Join(tried, ", "))
+}
+
+// probeRemote asks the remote host for its operating system name, as
+// printed by uname -s, and which of the backends' commands it has.
+func probeRemote(ctx context.Context, runner CmdRunner, candidates []Backend) (string, map[string]bool, error) {
+	script := "uname -s"
+	for _, b := range candidates {
+		script += "; command -v " + b.Command
+	}
+	// command -v fails for missing commands; what it prints is enough.
+	out, err := runner.Run(ctx, "sh", "-c", script+"; true")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to probe remote host: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	commands := make(map[string]bool)
+	for _, line := range lines[1:] {
+		commands[path.Base(strings.TrimSpace(line))] = true
+	}
+	return strings.TrimSpace(lines[0]), commands, nil
+}
+
+// commandAvailable reports whether an executable is on PATH.
+func commandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// procAvailable reports whether the /proc socket tables can be read.
+func procAvailable() bool {
+	_, err := os.Stat("/proc/net/tcp")
+	return err == nil
+}
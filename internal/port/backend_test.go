@@ -0,0 +1,72 @@
+package port
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBackendNames(t *testing.T) {
+	names := BackendNames()
+	if names[0] != Auto {
+		t.Errorf("first name: got %q, want %q", names[0], Auto)
+	}
+
+	seen := make(map[string]bool)
+	for _, n := range names {
+		if seen[n] {
+			t.Errorf("duplicate backend name %q", n)
+		}
+		seen[n] = true
+	}
+	for _, want := range []string{"lsof", "proc", "netlink", "netstat", "ss"} {
+		if !seen[want] {
+			t.Errorf("missing backend %q", want)
+		}
+	}
+}
+
+func TestNewScanner_Unknown(t *testing.T) {
+	_, _, err := NewScanner("bogus", &MockCmdRunner{})
+	if err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+	if !strings.Contains(err.Error(), "lsof") {
+		t.Errorf("error should list valid backends, got %q", err)
+	}
+}
+
+func TestNewScanner_Explicit(t *testing.T) {
+	for _, b := range Backends() {
+		if !b.Available() {
+			continue
+		}
+		_, name, err := NewScanner(strings.ToUpper(b.Name), &MockCmdRunner{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", b.Name, err)
+		}
+		if name != b.Name {
+			t.Errorf("name: got %q, want %q", name, b.Name)
+		}
+	}
+}
+
+func TestNewScanner_AutoPicksFirstAvailable(t *testing.T) {
+	var want string
+	for _, b := range Backends() {
+		if b.Available() {
+			want = b.Name
+			break
+		}
+	}
+	if want == "" {
+		t.Skip("no backend available on this host")
+	}
+
+	_, name, err := NewScanner("", &MockCmdRunner{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != want {
+		t.Errorf("auto: got %q, want %q", name, want)
+	}
+}
@@ -0,0 +1,204 @@
+package port
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Container runtimes reported in Container.Runtime.
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimePodman     = "podman"
+	RuntimeCRIO       = "cri-o"
+	RuntimeKubernetes = "kubernetes"
+)
+
+// Container identifies the container a process runs in. The zero value
+// means the process is not in a recognized container.
+type Container struct {
+	// Runtime is one of the Runtime constants. Processes in Kubernetes
+	// pods report RuntimeKubernetes whichever CRI runtime runs them.
+	Runtime string
+	// ID is the full 64-character container ID.
+	ID string
+	// Pod is the UID of the Kubernetes pod, if any.
+	Pod string
+}
+
+// IsZero reports whether c is the zero Container.
+func (c Container) IsZero() bool {
+	return c == Container{}
+}
+
+// ShortID returns the 12-character form of the ID used by docker ps.
+func (c Container) ShortID() string {
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+// String returns "runtime:shortid", or "" for the zero Container.
+func (c Container) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return c.Runtime + ":" + c.ShortID()
+}
+
+// Match reports whether c is selected by a --container argument: a runtime
+// name such as "docker", or a prefix of the container ID.
+func (c Container) Match(spec string) bool {
+	if c.IsZero() || spec == "" {
+		return false
+	}
+	spec = strings.ToLower(spec)
+	return spec == c.Runtime || strings.HasPrefix(c.ID, spec)
+}
+
+// scopePrefixes maps the prefixes systemd-managed runtimes give container
+// scopes, as in "docker-<id>.scope", to the runtime.
+var scopePrefixes = []struct {
+	prefix  string
+	runtime string
+}{
+	{"docker-", RuntimeDocker},
+	{"cri-containerd-", RuntimeContainerd},
+	{"containerd-", RuntimeContainerd},
+	{"crio-", RuntimeCRIO},
+	{"libpod-", RuntimePodman},
+}
+
+// ParseCgroup finds the container in the contents of /proc/<pid>/cgroup.
+// It understands both the cgroup v1 format, with one line per hierarchy,
+// and the single "0::/path" line of cgroup v2, for the cgroupfs and
+// systemd cgroup drivers.
+func ParseCgroup(data string) Container {
+	for _, line := range strings.Split(data, "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if c := parseCgroupPath(parts[2]); !c.IsZero() {
+			return c
+		}
+	}
+	return Container{}
+}
+
+// parseCgroupPath looks for a container ID in one cgroup path, such as
+// "/system.slice/docker-<id>.scope" or "/kubepods/burstable/pod<uid>/<id>".
+func parseCgroupPath(path string) Container {
+	var c Container
+	components := strings.Split(strings.Trim(path, "/"), "/")
+	for i, comp := range components {
+		if pod, ok := podUID(comp); ok {
+			c.Pod = pod
+			continue
+		}
+
+		name := strings.TrimSuffix(comp, ".scope")
+		// conmon is podman's monitor process, not the container.
+		if strings.HasPrefix(name, "libpod-conmon-") {
+			continue
+		}
+		for _, sp := range scopePrefixes {
+			if id, ok := strings.CutPrefix(name, sp.prefix); ok && isContainerID(id) {
+				c.Runtime, c.ID = sp.runtime, id
+				break
+			}
+		}
+		if c.ID == "" && isContainerID(name) && i > 0 {
+			// cgroupfs driver: the runtime is named by a parent directory.
+			c.Runtime, c.ID = parentRuntime(components[:i]), name
+		}
+		if c.ID != "" {
+			break
+		}
+	}
+
+	if c.ID == "" || c.Runtime == "" {
+		return Container{}
+	}
+	if strings.HasPrefix(components[0], "kubepods") {
+		c.Runtime = RuntimeKubernetes
+	}
+	return c
+}
+
+// parentRuntime names the runtime from the cgroup directories above a bare
+// container ID, as laid out by the cgroupfs driver.
+func parentRuntime(parents []string) string {
+	switch {
+	case strings.HasPrefix(parents[0], "kubepods"):
+		return RuntimeKubernetes
+	case parents[len(parents)-1] == "docker":
+		return RuntimeDocker
+	case parents[len(parents)-1] == "libpod_parent":
+		return RuntimePodman
+	}
+	return ""
+}
+
+// podUID extracts the pod UID from "pod<uid>" (cgroupfs) or
+// "kubepods-<qos>-pod<uid_with_underscores>.slice" (systemd).
+func podUID(comp string) (string, bool) {
+	name := strings.TrimSuffix(comp, ".slice")
+	i := strings.LastIndex(name, "pod")
+	if i < 0 || (i > 0 && name[i-1] != '-') {
+		return "", false
+	}
+	uid := name[i+len("pod"):]
+	if len(uid) != 36 {
+		return "", false
+	}
+	return strings.ReplaceAll(uid, "_", "-"), true
+}
+
+// isContainerID reports whether s is a 64-character hex container ID.
+func isContainerID(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// AttachContainers fills in the Container of every entry whose process
+// runs in a container. It is a no-op where /proc is not available.
+func AttachContainers(entries []PortEntry) {
+	attachContainers("/proc", entries)
+}
+
+func attachContainers(root string, entries []PortEntry) {
+	seen := make(map[int]Container)
+	for i := range entries {
+		pid := entries[i].PID
+		c, ok := seen[pid]
+		if !ok {
+			c = readContainer(root, pid)
+			seen[pid] = c
+		}
+		entries[i].Container = c
+	}
+}
+
+// readContainer reads <root>/<pid>/cgroup.
+func readContainer(root string, pid int) Container {
+	if pid <= 0 {
+		return Container{}
+	}
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return Container{}
+	}
+	return ParseCgroup(string(data))
+}
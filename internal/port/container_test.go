@@ -0,0 +1,113 @@
+package port
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testContainerID = "3f4a1c9e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f"
+	testPodUID      = "6f1c2d3e-4b5a-4c6d-8e7f-901a2b3c4d5e"
+)
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		file    string
+		runtime string
+		pod     string
+	}{
+		{"v1-docker", RuntimeDocker, ""},
+		{"v1-kubepods", RuntimeKubernetes, testPodUID},
+		{"v1-host", "", ""},
+		{"v2-docker", RuntimeDocker, ""},
+		{"v2-containerd", RuntimeContainerd, ""},
+		{"v2-podman", RuntimePodman, ""},
+		{"v2-podman-conmon", "", ""},
+		{"v2-kubepods", RuntimeKubernetes, testPodUID},
+		{"v2-host", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "cgroup", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := ParseCgroup(string(data))
+			if tt.runtime == "" {
+				if !c.IsZero() {
+					t.Fatalf("expected no container, got %+v", c)
+				}
+				return
+			}
+			if c.Runtime != tt.runtime {
+				t.Errorf("runtime: got %q, want %q", c.Runtime, tt.runtime)
+			}
+			if c.ID != testContainerID {
+				t.Errorf("id: got %q, want %q", c.ID, testContainerID)
+			}
+			if c.Pod != tt.pod {
+				t.Errorf("pod: got %q, want %q", c.Pod, tt.pod)
+			}
+		})
+	}
+}
+
+func TestContainer_Match(t *testing.T) {
+	c := Container{Runtime: RuntimeDocker, ID: testContainerID}
+
+	if got := c.String(); got != "docker:3f4a1c9e8b7d" {
+		t.Errorf("String: got %q", got)
+	}
+
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"docker", true},
+		{"Docker", true},
+		{"3f4a1c9e", true},
+		{testContainerID, true},
+		{"podman", false},
+		{"4a1c", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.spec); got != tt.want {
+			t.Errorf("Match(%q): got %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	if (Container{}).Match("docker") {
+		t.Error("zero container should not match")
+	}
+}
+
+func TestAttachContainers(t *testing.T) {
+	root := writeProcFixture(t)
+	data, err := os.ReadFile(filepath.Join("testdata", "cgroup", "v2-docker"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "300", "cgroup"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []PortEntry{
+		{Port: 80, PID: 100, Process: "nginx"},
+		{Port: 3000, PID: 300, Process: "node"},
+		{Port: 3001, PID: 300, Process: "node"},
+	}
+	attachContainers(root, entries)
+
+	if !entries[0].Container.IsZero() {
+		t.Errorf("nginx: expected no container, got %+v", entries[0].Container)
+	}
+	for _, e := range entries[1:] {
+		if e.Container.Runtime != RuntimeDocker || e.Container.ID != testContainerID {
+			t.Errorf("%s: got container %+v", e, e.Container)
+		}
+	}
+}
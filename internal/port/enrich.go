@@ -0,0 +1,301 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of process times in /proc/<pid>/stat.
+// It is 100 on every architecture Linux supports.
+const clockTicks = 100
+
+// maxCachedProcs bounds processCache so that a long-running watch on a
+// busy host does not grow without limit.
+const maxCachedProcs = 4096
+
+// psTableFormat is the ps -o format read by enrichFromPs.
+const psTableFormat = "pid=,ppid=,user=,%cpu=,rss=,lstart=,command="
+
+// procKey identifies a process across scans. A PID alone is not enough
+// since PIDs are reused once a process exits.
+type procKey struct {
+	pid   int
+	start int64 // start time in nanoseconds since the epoch
+}
+
+// processCache builds the process table during a scan and fills in the
+// full command line, start time and parent PID of the scanned entries.
+// Command lines read from /proc are remembered per process, so repeated
+// scans in watch and the TUI only read them for processes that are new.
+type processCache struct {
+	mu       sync.Mutex
+	commands map[procKey]string
+}
+
+// tableSinkKey is the context key of the *map[int]Process that a scan
+// stores its process table in.
+type tableSinkKey struct{}
+
+// withTableSink returns a context under which a scan stores the process
+// table it builds in *table. The table travels with the scan rather than
+// the scanner, so overlapping scans cannot see each other's tables.
+func withTableSink(ctx context.Context, table *map[int]Process) context.Context {
+	return context.WithValue(ctx, tableSinkKey{}, table)
+}
+
+// storeTable hands the process table of a scan to the caller that asked
+// for it with withTableSink, if any.
+func storeTable(ctx context.Context, table map[int]Process) {
+	if sink, ok := ctx.Value(tableSinkKey{}).(*map[int]Process); ok {
+		*sink = table
+	}
+}
+
+// enrichFromProc builds the process table from the proc filesystem at
+// root. Every process's stat file is read; command lines are only read
+// for socket owners, and only once per process.
+func (c *processCache) enrichFromProc(ctx context.Context, root string, pids []int, entries []PortEntry) {
+	bootTime, ok := readBootTime(root)
+	if !ok {
+		return
+	}
+
+	owners := make(map[int]string)
+	for _, e := range entries {
+		owners[e.PID] = e.User
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	pageSize := int64(os.Getpagesize())
+	table := make(map[int]Process, len(pids))
+	for _, pid := range pids {
+		data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+		if err != nil {
+			continue
+		}
+		st, ok := parseProcStat(string(data), bootTime)
+		if !ok {
+			continue
+		}
+
+		p := Process{
+			PID:       pid,
+			PPID:      st.ppid,
+			Name:      st.comm,
+			Command:   st.comm,
+			StartTime: st.start,
+			MemRSS:    st.rssPages * pageSize,
+		}
+		if elapsed := now.Sub(st.start).Seconds(); elapsed > 0 {
+			p.CPUPercent = float64(st.cpuTicks) / clockTicks / elapsed * 100
+		}
+		if user, ok := owners[pid]; ok {
+			p.User = user
+			key := procKey{pid: pid, start: st.start.UnixNano()}
+			cmd, cached := c.commands[key]
+			if !cached {
+				cmd = readCmdline(root, pid)
+				c.storeCommand(key, cmd)
+			}
+			if cmd != "" {
+				p.Command = cmd
+			}
+		}
+		table[pid] = p
+	}
+
+	storeTable(ctx, table)
+	applyProcesses(entries, table)
+}
+
+// enrichFromPs builds the process table with a single ps invocation, for
+// platforms without a proc filesystem.
+func (c *processCache) enrichFromPs(ctx context.Context, runner CmdRunner, entries []PortEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	out, err := runner.Run(ctx, "ps", "-A", "-ww", "-o", psTableFormat)
+	if err != nil && len(out) == 0 {
+		return
+	}
+
+	owners := make(map[int]string)
+	for _, e := range entries {
+		owners[e.PID] = e.Process
+	}
+
+	table := make(map[int]Process)
+	for _, line := range strings.Split(string(out), "\n") {
+		p, ok := parsePsLine(line)
+		if !ok {
+			continue
+		}
+		if name, ok := owners[p.PID]; ok {
+			p.Name = name
+		}
+		table[p.PID] = p
+	}
+
+	storeTable(ctx, table)
+	applyProcesses(entries, table)
+}
+
+// storeCommand adds a command line to the cache. The caller holds c.mu.
+func (c *processCache) storeCommand(key procKey, cmd string) {
+	if c.commands == nil || len(c.commands) >= maxCachedProcs {
+		c.commands = make(map[procKey]string)
+	}
+	c.commands[key] = cmd
+}
+
+// applyProcesses copies the command line, parent and start time of each
+// entry's process from the table, and its user when the scan only found
+// a UID. Kernel threads and zombies have no command line, so the short
+// name is kept for them.
+func applyProcesses(entries []PortEntry, table map[int]Process) {
+	for i := range entries {
+		p, ok := table[entries[i].PID]
+		if !ok {
+			continue
+		}
+		if p.Command != "" {
+			entries[i].Command = p.Command
+		}
+		if p.User != "" && isNumeric(entries[i].User) {
+			entries[i].User = p.User
+		}
+		entries[i].PPID = p.PPID
+		entries[i].StartTime = p.StartTime
+	}
+}
+
+// readBootTime reads the "btime" line of <root>/stat.
+func readBootTime(root string) (time.Time, bool) {
+	data, err := os.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(rest), 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.Unix(secs, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// procStat holds the fields of /proc/<pid>/stat that are used.
+type procStat struct {
+	comm     string
+	ppid     int
+	start    time.Time
+	cpuTicks int64 // user plus system time
+	rssPages int64
+}
+
+// parseProcStat parses a /proc/<pid>/stat line. The command name in
+// parentheses may itself contain spaces and parentheses, so fields are
+// counted from the last ")".
+func parseProcStat(stat string, bootTime time.Time) (procStat, bool) {
+	open := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return procStat{}, false
+	}
+	// fields[0] is field 3 of proc(5), the state.
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+
+	var nums [4]int64
+	for i, field := range []int{1, 11, 12, 19} { // ppid, utime, stime, starttime
+		n, err := strconv.ParseInt(fields[field], 10, 64)
+		if err != nil {
+			return procStat{}, false
+		}
+		nums[i] = n
+	}
+	rss, _ := strconv.ParseInt(fields[21], 10, 64)
+
+	return procStat{
+		comm:     stat[open+1 : end],
+		ppid:     int(nums[0]),
+		start:    bootTime.Add(time.Duration(nums[3]) * time.Second / clockTicks),
+		cpuTicks: nums[1] + nums[2],
+		rssPages: rss,
+	}, true
+}
+
+// readCmdline reads the NUL-separated arguments of <root>/<pid>/cmdline
+// and joins them with spaces, the same as ps prints them.
+func readCmdline(root string, pid int) string {
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	data = bytes.TrimRight(data, "\x00")
+	return string(bytes.ReplaceAll(data, []byte{0}, []byte{' '}))
+}
+
+// parsePsLine parses a line of ps -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=.
+// lstart is five tokens such as "Thu Feb 13 10:30:00 2026", in local
+// time, and rss is in kilobytes.
+func parsePsLine(line string) (Process, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 11 {
+		return Process{}, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Process{}, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Process{}, false
+	}
+	start, err := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(fields[5:10], " "), time.Local)
+	if err != nil {
+		return Process{}, false
+	}
+	cpu, _ := strconv.ParseFloat(fields[3], 64)
+	rss, _ := strconv.ParseInt(fields[4], 10, 64)
+
+	command := strings.Join(fields[10:], " ")
+	return Process{
+		PID:        pid,
+		PPID:       ppid,
+		Name:       path.Base(fields[10]),
+		Command:    command,
+		User:       fields[2],
+		StartTime:  start,
+		CPUPercent: cpu,
+		MemRSS:     rss * 1024,
+	}, true
+}
+
+// isNumeric reports whether s is a non-empty string of ASCII digits.
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package port
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const bootTime = 1760000000
+
+// writeEnrichFixture adds a boot time and stat/cmdline files for the node
+// process (pid 300, parent 1) to the proc fixture.
+func writeEnrichFixture(t *testing.T) string {
+	t.Helper()
+	root := writeProcFixture(t)
+	writeFixtureFile(t, root, "stat", "cpu  1 2 3 4\nbtime 1760000000\nprocesses 1000\n")
+	writeNodeStat(t, root, 4200)
+	writeFixtureFile(t, root, "300/cmdline", "node\x00server.js\x00--port\x003000\x00")
+	return root
+}
+
+// writeNodeStat writes pid 300's stat file with the given start time in
+// clock ticks after boot.
+func writeNodeStat(t *testing.T, root string, startTicks int) {
+	t.Helper()
+	stat := "300 (node (worker)) S 1 300 300 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 11 0 " +
+		strconv.Itoa(startTicks) + " 1000000 2000 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n"
+	writeFixtureFile(t, root, "300/stat", stat)
+}
+
+func writeFixtureFile(t *testing.T, root, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	boot := time.Unix(bootTime, 0)
+	stat := "300 (a) b) c) S 42 300 300 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 11 0 250 1000000 2000"
+
+	st, ok := parseProcStat(stat, boot)
+	if !ok {
+		t.Fatal("failed to parse stat line")
+	}
+	if st.comm != "a) b) c" {
+		t.Errorf("comm: got %q", st.comm)
+	}
+	if st.ppid != 42 {
+		t.Errorf("ppid: got %d, want 42", st.ppid)
+	}
+	if want := boot.Add(2500 * time.Millisecond); !st.start.Equal(want) {
+		t.Errorf("start: got %v, want %v", st.start, want)
+	}
+	if st.cpuTicks != 15 || st.rssPages != 2000 {
+		t.Errorf("cpu/rss: got %d/%d, want 15/2000", st.cpuTicks, st.rssPages)
+	}
+
+	if _, ok := parseProcStat("300 (truncated", boot); ok {
+		t.Error("expected failure for truncated stat line")
+	}
+}
+
+func TestProcScanner_Enrich(t *testing.T) {
+	root := writeEnrichFixture(t)
+	s := NewProcScanner(root)
+
+	entries, err := s.FindByPort(context.Background(), 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	e := entries[0]
+	if e.Command != "node server.js --port 3000" {
+		t.Errorf("command: got %q", e.Command)
+	}
+	if e.Process != "node" {
+		t.Errorf("process: got %q, want the short name node", e.Process)
+	}
+	if e.PPID != 1 {
+		t.Errorf("ppid: got %d, want 1", e.PPID)
+	}
+	if want := time.Unix(bootTime+42, 0); !e.StartTime.Equal(want) {
+		t.Errorf("start: got %v, want %v", e.StartTime, want)
+	}
+
+	// Processes without stat files keep their short name.
+	entries, err = s.FindByPort(context.Background(), 8080)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) == 0 || entries[0].Command != "nginx" || !entries[0].StartTime.IsZero() {
+		t.Errorf("expected unenriched nginx entry, got %+v", entries)
+	}
+}
+
+func TestProcScanner_EnrichCache(t *testing.T) {
+	root := writeEnrichFixture(t)
+	s := NewProcScanner(root)
+	ctx := context.Background()
+
+	if _, err := s.FindByPort(ctx, 3000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The same process is served from the cache.
+	writeFixtureFile(t, root, "300/cmdline", "node\x00changed.js\x00")
+	entries, err := s.FindByPort(ctx, 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[0].Command != "node server.js --port 3000" {
+		t.Errorf("expected cached command, got %q", entries[0].Command)
+	}
+
+	// A new process reusing the PID has a different start time.
+	writeNodeStat(t, root, 9000)
+	entries, err = s.FindByPort(ctx, 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entries[0].Command != "node changed.js" {
+		t.Errorf("expected command of the new process, got %q", entries[0].Command)
+	}
+}
+
+func TestLsofScanner_EnrichFindByProcess(t *testing.T) {
+	runner := &MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			"lsof +c 0 -F pcuLfatPnT -iTCP -iUDP -sTCP:LISTEN -P -n": {Output: []byte(
+				"p5678\ncnode\nu501\nf8\nau\ntIPv6\nPTCP\nn*:3000\nTST=LISTEN\n" +
+					"p5679\ncnode\nu501\nf9\nau\ntIPv4\nPTCP\nn127.0.0.1:9229\nTST=LISTEN\n")},
+			"ps -A -ww -o " + psTableFormat: {Output: []byte(
+				"  812     1 alice  0.0  2048 Thu Feb 13 10:29:58 2026 /bin/zsh\n" +
+					" 5678   812 alice  1.5 81920 Thu Feb 13 10:30:00 2026 /usr/local/bin/node /srv/api/server.js\n" +
+					" 5679   812 alice  0.2 40960 Thu Feb 13 10:31:07 2026 node --inspect worker.js\n")},
+		},
+	}
+	s := NewLsofScanner(runner)
+
+	entries, err := s.FindByProcess(context.Background(), "server.js")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].PID != 5678 {
+		t.Fatalf("expected only PID 5678, got %v", entries)
+	}
+
+	e := entries[0]
+	if e.Command != "/usr/local/bin/node /srv/api/server.js" {
+		t.Errorf("command: got %q", e.Command)
+	}
+	if e.PPID != 812 {
+		t.Errorf("ppid: got %d, want 812", e.PPID)
+	}
+	want := time.Date(2026, time.February, 13, 10, 30, 0, 0, time.Local)
+	if !e.StartTime.Equal(want) {
+		t.Errorf("start: got %v, want %v", e.StartTime, want)
+	}
+}
+
+func TestParsePsLine(t *testing.T) {
+	p, ok := parsePsLine("  812     1 root  0.0  7600 Mon Feb  2 09:05:00 2026 /usr/sbin/sshd -D [listener]")
+	if !ok {
+		t.Fatal("failed to parse ps line")
+	}
+	if p.PID != 812 || p.PPID != 1 || p.User != "root" {
+		t.Errorf("pid/ppid/user: got %d/%d/%s", p.PID, p.PPID, p.User)
+	}
+	if p.Name != "sshd" || p.Command != "/usr/sbin/sshd -D [listener]" {
+		t.Errorf("name/command: got %q/%q", p.Name, p.Command)
+	}
+	if p.MemRSS != 7600*1024 {
+		t.Errorf("rss: got %d", p.MemRSS)
+	}
+
+	if _, ok := parsePsLine("p5678"); ok {
+		t.Error("expected failure for non-ps output")
+	}
+}
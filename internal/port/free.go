@@ -0,0 +1,210 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// minUnreserved is the first port that unprivileged processes may bind.
+const minUnreserved = 1024
+
+// defaultEphemeral is the IANA dynamic port range, which macOS and
+// Windows use for outgoing connections. Linux reports its own range.
+var defaultEphemeral = portRange{49152, 65535}
+
+// FreeOptions selects the ports FindFree returns.
+type FreeOptions struct {
+	Count    int      // how many ports to return
+	Ports    *PortSet // ports to choose from; nil for any
+	Near     int      // preferred port, tried first and then by distance; 0 for the lowest
+	Protocol Protocol // TCP or UDP
+	Family   Family   // IPv4, IPv6, or DualStack to require both
+
+	// InUse reports ports to pass over without trying them, such as
+	// ports a scan found in use in other network namespaces.
+	InUse func(port int) bool
+}
+
+// FreeRanges describes the ports FindFree never returns.
+type FreeRanges struct {
+	Ephemeral string // e.g. "32768-60999"
+	Reserved  string // ports reserved by the administrator, if any
+}
+
+// FindFree returns ports that can be bound on all interfaces, testing each
+// candidate by binding it. Ports below 1024, the ephemeral range the
+// kernel assigns outgoing connections from, and ports reserved with
+// ip_local_reserved_ports on Linux are skipped. It returns fewer ports
+// than asked for, with no error, if the candidates run out.
+func FindFree(ctx context.Context, opts FreeOptions) ([]int, FreeRanges, error) {
+	return findFree(ctx, "/proc", opts)
+}
+
+func findFree(ctx context.Context, root string, opts FreeOptions) ([]int, FreeRanges, error) {
+	ephemeral := ephemeralRange(root)
+	reserved := reservedPorts(root)
+	ranges := FreeRanges{Ephemeral: ephemeral.String()}
+	if reserved != nil {
+		ranges.Reserved = reserved.String()
+	}
+
+	networks, err := bindNetworks(opts.Protocol, opts.Family)
+	if err != nil {
+		return nil, ranges, err
+	}
+
+	free := make([]int, 0, opts.Count)
+	for _, p := range candidates(opts.Near) {
+		if len(free) >= opts.Count {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return free, ranges, err
+		}
+		if p < minUnreserved || (p >= ephemeral.lo && p <= ephemeral.hi) {
+			continue
+		}
+		if reserved != nil && reserved.Match(p) {
+			continue
+		}
+		if !opts.Ports.Match(p) || (opts.InUse != nil && opts.InUse(p)) {
+			continue
+		}
+		if canBind(networks, p) {
+			free = append(free, p)
+		}
+	}
+	return free, ranges, nil
+}
+
+// candidates returns every port in the order to try them: by distance
+// from near, higher first on ties, or in ascending order.
+func candidates(near int) []int {
+	ports := make([]int, 0, 65535)
+	if near <= 0 || near > 65535 {
+		for p := 1; p <= 65535; p++ {
+			ports = append(ports, p)
+		}
+		return ports
+	}
+	ports = append(ports, near)
+	for d := 1; near+d <= 65535 || near-d >= 1; d++ {
+		if near+d <= 65535 {
+			ports = append(ports, near+d)
+		}
+		if near-d >= 1 {
+			ports = append(ports, near-d)
+		}
+	}
+	return ports
+}
+
+// bindNetworks returns the networks to bind a candidate on for a protocol
+// and address family. DualStack leaves out IPv6 on hosts without it.
+func bindNetworks(proto Protocol, family Family) ([]string, error) {
+	var base string
+	switch proto {
+	case TCP, "":
+		base = "tcp"
+	case UDP:
+		base = "udp"
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", proto)
+	}
+
+	switch family {
+	case IPv4:
+		return []string{base + "4"}, nil
+	case IPv6:
+		if !ipv6Supported() {
+			return nil, fmt.Errorf("IPv6 is not available on this host")
+		}
+		return []string{base + "6"}, nil
+	case DualStack, "":
+		if !ipv6Supported() {
+			return []string{base + "4"}, nil
+		}
+		return []string{base + "4", base + "6"}, nil
+	}
+	return nil, fmt.Errorf("unsupported address family %q", family)
+}
+
+// ipv6Supported reports whether IPv6 sockets can be bound.
+func ipv6Supported() bool {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// canBind reports whether the port can be bound on all interfaces on
+// every network. Binding the wildcard address also fails if any specific
+// address holds the port.
+func canBind(networks []string, port int) bool {
+	for _, network := range networks {
+		host := "0.0.0.0"
+		if strings.HasSuffix(network, "6") {
+			host = "::"
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+		var err error
+		if strings.HasPrefix(network, "udp") {
+			var pc net.PacketConn
+			if pc, err = net.ListenPacket(network, addr); err == nil {
+				pc.Close()
+			}
+		} else {
+			var ln net.Listener
+			if ln, err = net.Listen(network, addr); err == nil {
+				ln.Close()
+			}
+		}
+		if err != nil && !errors.Is(err, syscall.EAFNOSUPPORT) {
+			return false
+		}
+	}
+	return true
+}
+
+// ephemeralRange reads the range Linux assigns local ports from, or
+// returns the IANA dynamic range elsewhere.
+func ephemeralRange(root string) portRange {
+	data, err := os.ReadFile(filepath.Join(root, "sys/net/ipv4/ip_local_port_range"))
+	if err != nil {
+		return defaultEphemeral
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return defaultEphemeral
+	}
+	lo, err1 := strconv.Atoi(fields[0])
+	hi, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || lo > hi {
+		return defaultEphemeral
+	}
+	return portRange{lo, hi}
+}
+
+// reservedPorts reads the ports the administrator reserved on Linux, in
+// the same "8000-8010,9000" syntax as a port set, or returns nil.
+func reservedPorts(root string) *PortSet {
+	data, err := os.ReadFile(filepath.Join(root, "sys/net/ipv4/ip_local_reserved_ports"))
+	if err != nil {
+		return nil
+	}
+	set, err := ParsePortSet(strings.TrimSpace(string(data)), nil)
+	if err != nil {
+		return nil
+	}
+	return set
+}
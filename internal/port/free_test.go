@@ -0,0 +1,125 @@
+package port
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeProcSys writes ip_local_port_range and ip_local_reserved_ports under
+// a temporary proc root.
+func fakeProcSys(t *testing.T, portRange, reserved string) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "sys/net/ipv4")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "ip_local_port_range"), []byte(portRange), 0o644)
+	os.WriteFile(filepath.Join(dir, "ip_local_reserved_ports"), []byte(reserved), 0o644)
+	return root
+}
+
+// listen holds a port on all IPv4 interfaces for the rest of the test.
+func listen(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "0.0.0.0:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestFindFree_SkipsBoundPorts(t *testing.T) {
+	root := fakeProcSys(t, "1025\t1030\n", "\n")
+	held := listen(t)
+
+	set, _ := ParsePortSet(portRange{held - 1, held + 1}.String(), nil)
+	got, _, err := findFree(context.Background(), root, FreeOptions{
+		Count: 3, Ports: set, Near: held, Protocol: TCP, Family: IPv4,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range got {
+		if p == held {
+			t.Errorf("expected the held port %d to be skipped, got %v", held, got)
+		}
+	}
+}
+
+func TestFindFree_SkipsReservedAndEphemeral(t *testing.T) {
+	root := fakeProcSys(t, "40000 40009\n", "40012,40014-40015\n")
+	set, _ := ParsePortSet("1000-1030,39998-40016", nil)
+
+	got, ranges, err := findFree(context.Background(), root, FreeOptions{
+		Count: 100, Ports: set, Protocol: TCP, Family: IPv4,
+		InUse: func(p int) bool { return p == 40011 },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ranges.Ephemeral != "40000-40009" || ranges.Reserved != "40012,40014-40015" {
+		t.Errorf("ranges: got %+v", ranges)
+	}
+	for _, p := range got {
+		switch {
+		case p < 1024:
+			t.Errorf("returned privileged port %d", p)
+		case p >= 40000 && p <= 40009:
+			t.Errorf("returned ephemeral port %d", p)
+		case p == 40011 || p == 40012 || p == 40014 || p == 40015:
+			t.Errorf("returned reserved or in-use port %d", p)
+		}
+	}
+	if len(got) == 0 {
+		t.Error("expected some free ports")
+	}
+}
+
+func TestFindFree_Count(t *testing.T) {
+	root := fakeProcSys(t, "60000 61000", "")
+	got, _, err := findFree(context.Background(), root, FreeOptions{Count: 2, Protocol: UDP, Family: IPv4})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] < 1024 || got[1] <= got[0] {
+		t.Errorf("expected two ascending unprivileged ports, got %v", got)
+	}
+}
+
+func TestFindFree_Errors(t *testing.T) {
+	root := fakeProcSys(t, "60000 61000", "")
+	if _, _, err := findFree(context.Background(), root, FreeOptions{Count: 1, Protocol: Unix}); err == nil {
+		t.Error("expected an error for Unix sockets")
+	}
+	if _, _, err := findFree(context.Background(), root, FreeOptions{Count: 1, Family: "IPv5"}); err == nil {
+		t.Error("expected an error for an unknown family")
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	got := candidates(3000)[:5]
+	if want := []int{3000, 3001, 2999, 3002, 2998}; !reflect.DeepEqual(got, want) {
+		t.Errorf("near 3000: got %v, want %v", got, want)
+	}
+	if got := candidates(65535)[:3]; !reflect.DeepEqual(got, []int{65535, 65534, 65533}) {
+		t.Errorf("near 65535: got %v", got)
+	}
+	if all := candidates(0); len(all) != 65535 || all[0] != 1 {
+		t.Errorf("ascending: got %d ports starting at %d", len(all), all[0])
+	}
+}
+
+func TestEphemeralRange(t *testing.T) {
+	if got := ephemeralRange(fakeProcSys(t, "32768\t60999\n", "")); got != (portRange{32768, 60999}) {
+		t.Errorf("got %v", got)
+	}
+	if got := ephemeralRange(t.TempDir()); got != defaultEphemeral {
+		t.Errorf("expected the IANA range without /proc, got %v", got)
+	}
+}
@@ -0,0 +1,240 @@
+package port
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Address families and protocol numbers used in sock_diag requests.
+// They are spelled out here so the message encoding builds on every OS.
+const (
+	diagAFInet  = 2
+	diagAFInet6 = 10
+
+	diagProtoTCP = 6
+	diagProtoUDP = 17
+)
+
+// Sizes of the fixed-length inet_diag structures (linux/inet_diag.h).
+const (
+	inetDiagReqV2Len = 56
+	inetDiagMsgLen   = 72
+)
+
+// diagAttrV6Only is the INET_DIAG_SKV6ONLY attribute type.
+const diagAttrV6Only = 11
+
+// State bitmasks for inet_diag requests: bit N selects kernel state N.
+const (
+	diagAllStates    = 0xffffffff
+	diagListenStates = 1 << 10 // TCP_LISTEN
+)
+
+// diagDumpFunc dumps the sockets of one family/protocol pair whose state
+// is in the states bitmask.
+type diagDumpFunc func(ctx context.Context, family, proto uint8, states uint32) ([]socketInfo, error)
+
+// NetlinkScanner implements Scanner on Linux by querying the kernel over
+// NETLINK_SOCK_DIAG. Socket inodes are resolved to processes through
+// /proc/<pid>/fd, the same as ProcScanner.
+type NetlinkScanner struct {
+	procs *ProcScanner
+	dump  diagDumpFunc
+}
+
+// NewNetlinkScanner creates a new scanner backed by inet_diag.
+func NewNetlinkScanner() *NetlinkScanner {
+	return &NetlinkScanner{
+		procs: NewProcScanner(""),
+		dump:  dumpInetDiag,
+	}
+}
+
+// ListPorts returns all listening TCP ports and all UDP sockets.
+func (s *NetlinkScanner) ListPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.scan(ctx, diagListenStates, nil)
+}
+
+// ListAllPorts returns all connections including ESTABLISHED.
+func (s *NetlinkScanner) ListAllPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.scan(ctx, diagAllStates, nil)
+}
+
+// FindByPort returns all entries matching the given port number.
+func (s *NetlinkScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
+	return s.scan(ctx, diagAllStates, func(sock socketInfo) bool {
+		return sock.localPort == port
+	})
+}
+
+// FindByProcess returns all entries matching the given process name.
+func (s *NetlinkScanner) FindByProcess(ctx context.Context, name string) ([]PortEntry, error) {
+	entries, err := s.ListPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchProcess(entries, name), nil
+}
+
+// scan dumps TCP sockets in tcpStates and all UDP sockets for both address
+// families, then joins them with their owning processes. sock_diag only
+// sees the caller's network namespace, so sockets in other namespaces are
+// read from /proc/<pid>/net instead.
+func (s *NetlinkScanner) scan(ctx context.Context, tcpStates uint32, keep func(socketInfo) bool) ([]PortEntry, error) {
+	queries := []struct {
+		family uint8
+		proto  uint8
+		states uint32
+	}{
+		{diagAFInet, diagProtoTCP, tcpStates},
+		{diagAFInet6, diagProtoTCP, tcpStates},
+		{diagAFInet, diagProtoUDP, diagAllStates},
+		{diagAFInet6, diagProtoUDP, diagAllStates},
+	}
+
+	dirs := s.procs.netnsDirs()
+	socks := make(map[uint64]socketInfo)
+	for _, q := range queries {
+		found, err := s.dump(ctx, q.family, q.proto, q.states)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query sock_diag: %w", err)
+		}
+		for _, sock := range found {
+			if keep != nil && !keep(sock) {
+				continue
+			}
+			sock.netns = dirs[0].netns
+			socks[sock.inode] = sock
+		}
+	}
+
+	// tcpStates is either diagAllStates or diagListenStates.
+	foreignKeep := func(sock socketInfo) bool {
+		if sock.proto == TCP && tcpStates != diagAllStates && sock.state != StateListen {
+			return false
+		}
+		return keep == nil || keep(sock)
+	}
+	for _, dir := range dirs[1:] {
+		readNetTables(dir, foreignKeep, socks)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.procs.joinOwners(ctx, socks)
+}
+
+// encodeInetDiagReq builds an inet_diag_req_v2 payload.
+func encodeInetDiagReq(family, proto uint8, states uint32) []byte {
+	b := make([]byte, inetDiagReqV2Len)
+	b[0] = family
+	b[1] = proto
+	binary.NativeEndian.PutUint32(b[4:8], states)
+	return b
+}
+
+// parseInetDiagMsg decodes an inet_diag_msg payload and its attributes.
+// Layout:
+//
+//	u8 family, u8 state, u8 timer, u8 retrans,
+//	inet_diag_sockid { be16 sport, be16 dport, be32 src[4], be32 dst[4], u32 if, u32 cookie[2] },
+//	u32 expires, u32 rqueue, u32 wqueue, u32 uid, u32 inode,
+//	struct rtattr attributes...
+func parseInetDiagMsg(b []byte, proto Protocol) (socketInfo, bool) {
+	if len(b) < inetDiagMsgLen {
+		return socketInfo{}, false
+	}
+
+	var family Family
+	var src, dst net.IP
+	switch b[0] {
+	case diagAFInet:
+		family = IPv4
+		src = net.IP(append([]byte(nil), b[8:12]...))
+		dst = net.IP(append([]byte(nil), b[24:28]...))
+	case diagAFInet6:
+		family = IPv6
+		src = net.IP(append([]byte(nil), b[8:24]...))
+		dst = net.IP(append([]byte(nil), b[24:40]...))
+	default:
+		return socketInfo{}, false
+	}
+
+	state := int(b[1])
+	localPort := int(binary.BigEndian.Uint16(b[4:6]))
+	remotePort := int(binary.BigEndian.Uint16(b[6:8]))
+	ifindex := int(binary.NativeEndian.Uint32(b[40:44]))
+	uid := binary.NativeEndian.Uint32(b[64:68])
+	inode := binary.NativeEndian.Uint32(b[68:72])
+
+	v6only := v6OnlyUnknown
+	if attr, ok := diagAttr(b[inetDiagMsgLen:], diagAttrV6Only); ok && len(attr) > 0 {
+		v6only = v6OnlyOff
+		if attr[0] != 0 {
+			v6only = v6OnlyOn
+		}
+	}
+	addr, family := bindAddr(src.String(), family, v6only)
+	remoteAddr, _ := peerAddr(dst.String(), remotePort)
+
+	zone := ""
+	if ifindex != 0 {
+		zone = ifaceName(ifindex)
+	}
+
+	return socketInfo{
+		proto:      proto,
+		family:     family,
+		localAddr:  addr,
+		zone:       zone,
+		localPort:  localPort,
+		remoteAddr: remoteAddr,
+		remotePort: remotePort,
+		state:      kernelStateName(proto, state, remotePort),
+		uid:        int(uid),
+		inode:      uint64(inode),
+	}, true
+}
+
+// diagAttr returns the payload of the first rtattr of the given type.
+func diagAttr(b []byte, typ uint16) ([]byte, bool) {
+	for len(b) >= 4 {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		t := binary.NativeEndian.Uint16(b[2:4])
+		if l < 4 || l > len(b) {
+			return nil, false
+		}
+		if t == typ {
+			return b[4:l], true
+		}
+		// Attributes are padded to 4-byte boundaries.
+		next := (l + 3) &^ 3
+		if next > len(b) {
+			return nil, false
+		}
+		b = b[next:]
+	}
+	return nil, false
+}
+
+// ifaceName resolves an interface index to its name, falling back to the
+// number as ss does.
+var ifaceName = func(index int) string {
+	if iface, err := net.InterfaceByIndex(index); err == nil {
+		return iface.Name
+	}
+	return strconv.Itoa(index)
+}
+
+// diagProtocol maps an IP protocol number to a Protocol.
+func diagProtocol(proto uint8) Protocol {
+	if proto == diagProtoUDP {
+		return UDP
+	}
+	return TCP
+}
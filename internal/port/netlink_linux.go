@@ -0,0 +1,89 @@
+//go:build linux
+
+package port
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// sockDiagByFamily is the SOCK_DIAG_BY_FAMILY netlink message type.
+const sockDiagByFamily = 20
+
+// NetlinkAvailable reports whether a NETLINK_SOCK_DIAG socket can be opened.
+func NetlinkAvailable() bool {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return false
+	}
+	syscall.Close(fd)
+	return true
+}
+
+// dumpInetDiag sends one inet_diag dump request and collects the replies.
+func dumpInetDiag(ctx context.Context, family, proto uint8, states uint32) ([]socketInfo, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	if err := syscall.Bind(fd, sa); err != nil {
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	payload := encodeInetDiagReq(family, proto, states)
+	req := make([]byte, syscall.NLMSG_HDRLEN+len(payload))
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], 1)
+	copy(req[syscall.NLMSG_HDRLEN:], payload)
+
+	if err := syscall.Sendto(fd, req, 0, sa); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	p := diagProtocol(proto)
+	buf := make([]byte, 64*1024)
+	var socks []socketInfo
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse netlink reply: %w", err)
+		}
+
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return socks, nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(m.Data[:4])); errno != 0 {
+						return nil, syscall.Errno(-errno)
+					}
+				}
+				return socks, nil
+			}
+			if sock, ok := parseInetDiagMsg(m.Data, p); ok {
+				socks = append(socks, sock)
+			}
+		}
+	}
+}
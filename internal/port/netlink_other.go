@@ -0,0 +1,18 @@
+//go:build !linux
+
+package port
+
+import (
+	"context"
+	"errors"
+)
+
+// NetlinkAvailable reports whether a NETLINK_SOCK_DIAG socket can be opened.
+// It is always false outside Linux.
+func NetlinkAvailable() bool {
+	return false
+}
+
+func dumpInetDiag(_ context.Context, _, _ uint8, _ uint32) ([]socketInfo, error) {
+	return nil, errors.New("netlink sock_diag is only supported on Linux")
+}
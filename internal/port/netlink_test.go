@@ -0,0 +1,222 @@
+package port
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+// fakeDiagMsg encodes an inet_diag_msg the way the kernel would.
+func fakeDiagMsg(family uint8, state uint8, src, dst string, sport, dport uint16, uid, inode uint32) []byte {
+	b := make([]byte, inetDiagMsgLen)
+	b[0] = family
+	b[1] = state
+	binary.BigEndian.PutUint16(b[4:6], sport)
+	binary.BigEndian.PutUint16(b[6:8], dport)
+	for i, addr := range []string{src, dst} {
+		off := 8 + 16*i
+		if ip := net.ParseIP(addr); family == diagAFInet {
+			copy(b[off:off+4], ip.To4())
+		} else {
+			copy(b[off:off+16], ip.To16())
+		}
+	}
+	binary.NativeEndian.PutUint32(b[64:68], uid)
+	binary.NativeEndian.PutUint32(b[68:72], inode)
+	return b
+}
+
+func TestEncodeInetDiagReq(t *testing.T) {
+	b := encodeInetDiagReq(diagAFInet6, diagProtoTCP, diagListenStates)
+	if len(b) != inetDiagReqV2Len {
+		t.Fatalf("length: got %d, want %d", len(b), inetDiagReqV2Len)
+	}
+	if b[0] != diagAFInet6 || b[1] != diagProtoTCP {
+		t.Errorf("family/protocol: got %d/%d", b[0], b[1])
+	}
+	if got := binary.NativeEndian.Uint32(b[4:8]); got != diagListenStates {
+		t.Errorf("states: got %#x, want %#x", got, diagListenStates)
+	}
+}
+
+func TestParseInetDiagMsg(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       []byte
+		proto     Protocol
+		wantPort  int
+		wantState State
+		wantUID   int
+		wantInode uint64
+	}{
+		{"tcp listen", fakeDiagMsg(diagAFInet, 10, "0.0.0.0", "", 8080, 0, 1000, 1001), TCP, 8080, "LISTEN", 1000, 1001},
+		{"tcp6 established", fakeDiagMsg(diagAFInet6, 1, "2001:db8::10", "2001:db8::1", 54321, 443, 0, 1003), TCP, 54321, "ESTABLISHED", 0, 1003},
+		{"tcp time wait", fakeDiagMsg(diagAFInet, 6, "127.0.0.1", "127.0.0.1", 5432, 50000, 0, 0), TCP, 5432, "TIME_WAIT", 0, 0},
+		{"udp unconnected", fakeDiagMsg(diagAFInet, 7, "0.0.0.0", "", 5353, 0, 0, 3001), UDP, 5353, "LISTEN", 0, 3001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sock, ok := parseInetDiagMsg(tt.msg, tt.proto)
+			if !ok {
+				t.Fatal("expected message to parse")
+			}
+			if sock.localPort != tt.wantPort {
+				t.Errorf("port: got %d, want %d", sock.localPort, tt.wantPort)
+			}
+			if sock.state != tt.wantState {
+				t.Errorf("state: got %q, want %q", sock.state, tt.wantState)
+			}
+			if sock.uid != tt.wantUID {
+				t.Errorf("uid: got %d, want %d", sock.uid, tt.wantUID)
+			}
+			if sock.inode != tt.wantInode {
+				t.Errorf("inode: got %d, want %d", sock.inode, tt.wantInode)
+			}
+		})
+	}
+}
+
+func TestParseInetDiagMsg_Bind(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        []byte
+		wantAddr   string
+		wantFamily Family
+		wantZone   string
+	}{
+		{"ipv4 loopback", fakeDiagMsg(diagAFInet, 10, "127.0.0.1", "", 5432, 0, 0, 1), "127.0.0.1", IPv4, ""},
+		{"ipv4 any", fakeDiagMsg(diagAFInet, 10, "0.0.0.0", "", 80, 0, 0, 1), "*", IPv4, ""},
+		{"ipv6 any dual-stack", withDiagAttr(fakeDiagMsg(diagAFInet6, 10, "::", "", 3000, 0, 0, 1), diagAttrV6Only, []byte{0}), "*", DualStack, ""},
+		{"ipv6 any v6only", withDiagAttr(fakeDiagMsg(diagAFInet6, 10, "::", "", 443, 0, 0, 1), diagAttrV6Only, []byte{1}), "*", IPv6, ""},
+		{"ipv6 any v6only unknown", fakeDiagMsg(diagAFInet6, 10, "::", "", 8443, 0, 0, 1), "*", IPv6, ""},
+		{"ipv6 loopback", fakeDiagMsg(diagAFInet6, 10, "::1", "", 5432, 0, 0, 1), "::1", IPv6, ""},
+		{"bound interface", withIfindex(fakeDiagMsg(diagAFInet6, 7, "fe80::1", "", 546, 0, 0, 1), 1), "fe80::1", IPv6, "if1"},
+	}
+
+	orig := ifaceName
+	ifaceName = func(index int) string { return fmt.Sprintf("if%d", index) }
+	defer func() { ifaceName = orig }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sock, ok := parseInetDiagMsg(tt.msg, TCP)
+			if !ok {
+				t.Fatal("expected message to parse")
+			}
+			if sock.localAddr != tt.wantAddr {
+				t.Errorf("addr: got %q, want %q", sock.localAddr, tt.wantAddr)
+			}
+			if sock.family != tt.wantFamily {
+				t.Errorf("family: got %q, want %q", sock.family, tt.wantFamily)
+			}
+			if sock.zone != tt.wantZone {
+				t.Errorf("zone: got %q, want %q", sock.zone, tt.wantZone)
+			}
+		})
+	}
+}
+
+// withDiagAttr appends an rtattr to an encoded inet_diag_msg.
+func withDiagAttr(msg []byte, typ uint16, payload []byte) []byte {
+	attr := make([]byte, (4+len(payload)+3)&^3)
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(4+len(payload)))
+	binary.NativeEndian.PutUint16(attr[2:4], typ)
+	copy(attr[4:], payload)
+	return append(msg, attr...)
+}
+
+// withIfindex sets idiag_if on an encoded inet_diag_msg.
+func withIfindex(msg []byte, index uint32) []byte {
+	binary.NativeEndian.PutUint32(msg[40:44], index)
+	return msg
+}
+
+func TestParseInetDiagMsg_Invalid(t *testing.T) {
+	if _, ok := parseInetDiagMsg(make([]byte, 10), TCP); ok {
+		t.Error("expected short message to be rejected")
+	}
+	if _, ok := parseInetDiagMsg(fakeDiagMsg(1, 10, "", "", 80, 0, 0, 1), TCP); ok {
+		t.Error("expected AF_UNIX message to be rejected")
+	}
+}
+
+// fakeDump serves the same sockets as the /proc fixture in proc_test.go.
+func fakeDump(_ context.Context, family, proto uint8, states uint32) ([]socketInfo, error) {
+	var msgs [][]byte
+	switch {
+	case family == diagAFInet && proto == diagProtoTCP:
+		msgs = [][]byte{
+			fakeDiagMsg(family, 10, "0.0.0.0", "", 8080, 0, 0, 1001),
+			fakeDiagMsg(family, 10, "127.0.0.1", "", 5432, 0, 0, 1002),
+			fakeDiagMsg(family, 1, "192.168.1.10", "93.184.216.34", 54321, 443, 0, 1003),
+			fakeDiagMsg(family, 8, "127.0.0.1", "127.0.0.1", 5432, 54322, 0, 1004),
+		}
+	case family == diagAFInet6 && proto == diagProtoTCP:
+		msgs = [][]byte{fakeDiagMsg(family, 10, "::", "", 3000, 0, 0, 2001)}
+	case family == diagAFInet && proto == diagProtoUDP:
+		msgs = [][]byte{fakeDiagMsg(family, 7, "0.0.0.0", "", 5353, 0, 0, 3001)}
+	}
+
+	var socks []socketInfo
+	for _, m := range msgs {
+		sock, _ := parseInetDiagMsg(m, diagProtocol(proto))
+		if states&(1<<m[1]) == 0 {
+			continue
+		}
+		socks = append(socks, sock)
+	}
+	return socks, nil
+}
+
+func TestNetlinkScanner_MatchesProcScanner(t *testing.T) {
+	root := writeProcFixture(t)
+	nl := &NetlinkScanner{procs: NewProcScanner(root), dump: fakeDump}
+	proc := NewProcScanner(root)
+
+	for _, all := range []bool{false, true} {
+		var got, want []PortEntry
+		var err error
+		if all {
+			got, err = nl.ListAllPorts(context.Background())
+		} else {
+			got, err = nl.ListPorts(context.Background())
+		}
+		if err != nil {
+			t.Fatalf("netlink scan: %v", err)
+		}
+		if all {
+			want, err = proc.ListAllPorts(context.Background())
+		} else {
+			want, err = proc.ListPorts(context.Background())
+		}
+		if err != nil {
+			t.Fatalf("proc scan: %v", err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("all=%v: got %d entries, want %d", all, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("all=%v [%d]: got %+v, want %+v", all, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestNetlinkScanner_DumpError(t *testing.T) {
+	nl := &NetlinkScanner{
+		procs: NewProcScanner(t.TempDir()),
+		dump: func(context.Context, uint8, uint8, uint32) ([]socketInfo, error) {
+			return nil, errors.New("permission denied")
+		},
+	}
+
+	if _, err := nl.ListPorts(context.Background()); err == nil {
+		t.Fatal("expected error when dump fails")
+	}
+}
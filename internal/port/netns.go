@@ -0,0 +1,154 @@
+package port
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// netnsRunDir is where `ip netns add` pins named network namespaces.
+const netnsRunDir = "/run/netns"
+
+// netnsDir is a net directory under the proc root through which the socket
+// tables of one network namespace are read.
+type netnsDir struct {
+	path  string
+	netns uint64
+}
+
+// netnsDirs returns the scanner's own net directory followed by one
+// /proc/<pid>/net directory for every other network namespace that a
+// process is in. Namespaces are found through /proc/<pid>/ns/net, which is
+// only readable for other users' processes when running as root.
+func (s *ProcScanner) netnsDirs() []netnsDir {
+	self, _ := readNetNSLink(filepath.Join(s.root, "self", "ns", "net"))
+	dirs := []netnsDir{{path: filepath.Join(s.root, "net"), netns: self}}
+
+	seen := map[uint64]bool{self: true}
+	for _, pid := range s.pids() {
+		ns, ok := readNetNSLink(filepath.Join(s.root, strconv.Itoa(pid), "ns", "net"))
+		if !ok || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		dirs = append(dirs, netnsDir{
+			path:  filepath.Join(s.root, strconv.Itoa(pid), "net"),
+			netns: ns,
+		})
+	}
+	return dirs
+}
+
+// readNetNSLink reads a namespace link such as "net:[4026531833]" and
+// returns its inode.
+func readNetNSLink(path string) (uint64, bool) {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return 0, false
+	}
+	return parseNetNSLink(link)
+}
+
+// parseNetNSLink parses "net:[4026531833]".
+func parseNetNSLink(link string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(link, "net:[")
+	if !ok || !strings.HasSuffix(rest, "]") {
+		return 0, false
+	}
+	ns, err := strconv.ParseUint(strings.TrimSuffix(rest, "]"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return ns, true
+}
+
+// SelfNetNS returns the network namespace whport itself runs in, or 0 if
+// it cannot be read.
+func SelfNetNS() uint64 {
+	ns, _ := readNetNSLink("/proc/self/ns/net")
+	return ns
+}
+
+// NetNSNames maps namespace inodes to the names given to them with
+// `ip netns add`.
+func NetNSNames() map[uint64]string {
+	return netnsNames(netnsRunDir)
+}
+
+func netnsNames(dir string) map[uint64]string {
+	names := make(map[uint64]string)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	for _, f := range files {
+		if ns, ok := fileInode(filepath.Join(dir, f.Name())); ok {
+			names[ns] = f.Name()
+		}
+	}
+	return names
+}
+
+// ResolveNetNS converts a --netns argument to a namespace inode. The
+// argument may be a namespace ID ("4026532600" or "net:[4026532600]"), the
+// PID of a process in the namespace, or a name from `ip netns add`. A bare
+// number that is not a PID must be a namespace that a process is in or
+// that is pinned by name, so that a mistyped PID is not taken as an ID.
+func ResolveNetNS(spec string) (uint64, error) {
+	return resolveNetNS("/proc", netnsRunDir, spec)
+}
+
+func resolveNetNS(procRoot, runDir, spec string) (uint64, error) {
+	if ns, ok := parseNetNSLink(spec); ok {
+		return ns, nil
+	}
+
+	if n, err := strconv.ParseUint(spec, 10, 64); err == nil {
+		// PIDs are small; namespace IDs are inode numbers.
+		if ns, ok := readNetNSLink(filepath.Join(procRoot, spec, "ns", "net")); ok {
+			return ns, nil
+		}
+		if !knownNetNS(procRoot, runDir, n) {
+			return 0, fmt.Errorf("unknown network namespace %q: no such PID or namespace ID", spec)
+		}
+		return n, nil
+	}
+
+	if strings.ContainsRune(spec, '/') {
+		return 0, fmt.Errorf("invalid network namespace name %q", spec)
+	}
+	ns, ok := fileInode(filepath.Join(runDir, spec))
+	if !ok {
+		return 0, fmt.Errorf("unknown network namespace %q", spec)
+	}
+	return ns, nil
+}
+
+// knownNetNS reports whether ns is a network namespace that a scan would
+// find, either through a process in it or pinned under runDir.
+func knownNetNS(procRoot, runDir string, ns uint64) bool {
+	for _, dir := range NewProcScanner(procRoot).netnsDirs() {
+		if dir.netns == ns {
+			return true
+		}
+	}
+	_, ok := netnsNames(runDir)[ns]
+	return ok
+}
+
+// fileInode returns the inode of path. For a namespace pinned under
+// /run/netns this is the namespace ID.
+func fileInode(path string) (uint64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Ino), true
+}
@@ -0,0 +1,191 @@
+package port
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const (
+	hostNetNS      = 4026531833
+	containerNetNS = 4026532600
+)
+
+const containerTCPFixture = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 6001 1 0000000000000000 100 0 0 10 0
+   1: 0200000A:0050 0300000A:C350 01 00000000:00000000 00:00000000 00000000     0        0 6002 1 0000000000000000 100 0 0 10 0
+`
+
+// writeNetNSFixture extends the proc fixture with namespace links: the
+// existing processes share the host namespace, and pid 600 runs nginx in a
+// container namespace with its own socket tables.
+func writeNetNSFixture(t *testing.T) string {
+	t.Helper()
+	root := writeProcFixture(t)
+
+	link := func(ns uint64, path string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("net:["+strconv.FormatUint(ns, 10)+"]", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link(hostNetNS, filepath.Join(root, "self", "ns", "net"))
+	for _, pid := range []int{100, 200, 300, 400, 500} {
+		link(hostNetNS, filepath.Join(root, strconv.Itoa(pid), "ns", "net"))
+	}
+
+	pidDir := filepath.Join(root, "600")
+	link(containerNetNS, filepath.Join(pidDir, "ns", "net"))
+	for name, data := range map[string]string{
+		"comm":    "nginx\n",
+		"net/tcp": containerTCPFixture,
+	} {
+		path := filepath.Join(pidDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(pidDir, "fd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for fd, inode := range map[int]string{6: "6001", 7: "6002"} {
+		if err := os.Symlink("socket:["+inode+"]", filepath.Join(pidDir, "fd", strconv.Itoa(fd))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestProcScanner_NetNS(t *testing.T) {
+	s := NewProcScanner(writeNetNSFixture(t))
+
+	entries, err := s.ListPorts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	for _, e := range entries {
+		want := uint64(hostNetNS)
+		if e.PID == 600 {
+			want = containerNetNS
+		}
+		if e.NetNS != want {
+			t.Errorf("%s: netns got %d, want %d", e, e.NetNS, want)
+		}
+	}
+
+	last := entries[len(entries)-1]
+	if last.PID != 600 || last.Port != 80 || last.State != StateListen {
+		t.Errorf("expected container nginx listening on 80, got %+v", last)
+	}
+}
+
+func TestProcScanner_NetNSConnections(t *testing.T) {
+	s := NewProcScanner(writeNetNSFixture(t))
+
+	entries, err := s.FindByPort(context.Background(), 80)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if got := entries[1].Remote(); got != "10.0.0.3:50000" {
+		t.Errorf("remote: got %q, want 10.0.0.3:50000", got)
+	}
+}
+
+func TestNetlinkScanner_NetNS(t *testing.T) {
+	root := writeNetNSFixture(t)
+	nl := &NetlinkScanner{procs: NewProcScanner(root), dump: fakeDump}
+
+	for _, all := range []bool{false, true} {
+		var got, want []PortEntry
+		var err error
+		if all {
+			got, err = nl.ListAllPorts(context.Background())
+			if err == nil {
+				want, err = NewProcScanner(root).ListAllPorts(context.Background())
+			}
+		} else {
+			got, err = nl.ListPorts(context.Background())
+			if err == nil {
+				want, err = NewProcScanner(root).ListPorts(context.Background())
+			}
+		}
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("all=%v: got %d entries, want %d", all, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("all=%v [%d]: got %+v, want %+v", all, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestResolveNetNS(t *testing.T) {
+	root := writeNetNSFixture(t)
+	runDir := t.TempDir()
+	named := filepath.Join(runDir, "sandbox")
+	if err := os.WriteFile(named, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	namedNS, ok := fileInode(named)
+	if !ok {
+		t.Fatal("failed to stat named namespace fixture")
+	}
+
+	tests := []struct {
+		spec    string
+		want    uint64
+		wantErr bool
+	}{
+		{"4026532600", containerNetNS, false},
+		{"net:[4026532600]", containerNetNS, false},
+		{"600", containerNetNS, false},
+		{"12345", 0, true}, // neither a PID nor a namespace in use
+		{"100", hostNetNS, false},
+		{"sandbox", namedNS, false},
+		{strconv.FormatUint(namedNS, 10), namedNS, false},
+		{"missing", 0, true},
+		{"../etc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := resolveNetNS(root, runDir, tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if names := netnsNames(runDir); names[namedNS] != "sandbox" {
+		t.Errorf("names: got %v, want sandbox", names)
+	}
+}
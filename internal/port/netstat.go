@@ -0,0 +1,168 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NetstatScanner implements Scanner using net-tools netstat on Linux.
+type NetstatScanner struct {
+	runner CmdRunner
+	users  userCache
+	procs  processCache
+}
+
+// NewNetstatScanner creates a new scanner backed by netstat.
+func NewNetstatScanner(runner CmdRunner) *NetstatScanner {
+	return &NetstatScanner{runner: runner, users: userCache{remote: RemoteHost(runner) != ""}}
+}
+
+// ListPorts returns all listening ports.
+func (s *NetstatScanner) ListPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.run(ctx, "-tunlpWe")
+}
+
+// ListAllPorts returns all connections including ESTABLISHED.
+func (s *NetstatScanner) ListAllPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.run(ctx, "-tuanpWe")
+}
+
+// FindByPort returns all entries matching the given port number.
+func (s *NetstatScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
+	entries, err := s.ListAllPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []PortEntry
+	for _, e := range entries {
+		if e.Port == port {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// FindByProcess returns all entries matching the given process name.
+func (s *NetstatScanner) FindByProcess(ctx context.Context, name string) ([]PortEntry, error) {
+	entries, err := s.ListPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchProcess(entries, name), nil
+}
+
+func (s *NetstatScanner) run(ctx context.Context, flags string) ([]PortEntry, error) {
+	out, err := s.runner.Run(ctx, "netstat", flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run netstat: %w", err)
+	}
+
+	entries := ParseNetstatOutput(string(out))
+	for i := range entries {
+		if uid, err := strconv.Atoi(entries[i].User); err == nil {
+			entries[i].User = s.users.name(uid)
+		}
+	}
+	s.procs.enrichFromPs(ctx, s.runner, entries)
+	return entries, nil
+}
+
+// ParseNetstatOutput parses the output from netstat -tunpWe on Linux.
+// Each socket line has fields:
+// Proto Recv-Q Send-Q Local Foreign [State] User Inode PID/Program
+// UDP sockets leave the State column blank. Sockets whose owner is not
+// visible ("-" in the last column) are skipped, the same as lsof.
+func ParseNetstatOutput(output string) []PortEntry {
+	var entries []PortEntry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		entry, ok := parseNetstatLine(line)
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseNetstatLine parses a single netstat socket line into a PortEntry.
+func parseNetstatLine(line string) (PortEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 8 {
+		return PortEntry{}, false
+	}
+
+	family := IPv4
+	if strings.HasSuffix(fields[0], "6") {
+		family = IPv6
+	}
+
+	var proto Protocol
+	switch strings.TrimSuffix(fields[0], "6") {
+	case "tcp":
+		proto = TCP
+	case "udp":
+		proto = UDP
+	default:
+		// Header lines and raw sockets.
+		return PortEntry{}, false
+	}
+
+	// Without a State column the remaining fields shift left by one.
+	rest := fields[5:]
+	var state State
+	if len(rest) == 4 {
+		state, _ = ParseState(rest[0])
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return PortEntry{}, false
+	}
+
+	pid, process, ok := strings.Cut(rest[2], "/")
+	if !ok {
+		return PortEntry{}, false
+	}
+	pidNum, err := strconv.Atoi(pid)
+	if err != nil {
+		return PortEntry{}, false
+	}
+
+	local, ok := parseEndpoint(fields[3])
+	if !ok || local.port < 0 {
+		return PortEntry{}, false
+	}
+	addr, family := bindAddr(local.addr, family, v6OnlyUnknown)
+
+	var remote endpoint
+	if ep, ok := parseEndpoint(fields[4]); ok {
+		remote = ep
+	}
+	remoteAddr, remotePort := peerAddr(remote.addr, remote.port)
+
+	if proto == UDP {
+		state = udpState(remotePort)
+	}
+
+	return PortEntry{
+		Process:    process,
+		PID:        pidNum,
+		User:       rest[0],
+		Protocol:   proto,
+		Port:       local.port,
+		State:      state,
+		Command:    process,
+		LocalAddr:  addr,
+		Family:     family,
+		Zone:       local.zone,
+		RemoteAddr: remoteAddr,
+		RemotePort: remotePort,
+	}, true
+}
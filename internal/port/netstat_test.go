@@ -0,0 +1,86 @@
+package port
+
+import (
+	"context"
+	"testing"
+)
+
+const netstatFixture = `Active Internet connections (servers and established)
+Proto Recv-Q Send-Q Local Address           Foreign Address         State       User       Inode      PID/Program name
+tcp        0      0 127.0.0.1:5432          0.0.0.0:*               LISTEN      0          1002       200/postgres
+tcp        0      0 0.0.0.0:2024            0.0.0.0:*               LISTEN      0          662        -
+tcp        0      0 192.168.1.10:54321      93.184.216.34:443       ESTABLISHED 1000       1003       300/node
+tcp6       0      0 :::3000                 :::*                    LISTEN      1000       2001       300/node
+udp        0      0 0.0.0.0:5353            0.0.0.0:*                           0          3001       400/avahi-daemon
+udp        0      0 10.0.0.2:41000          10.0.0.1:53             ESTABLISHED 0          3002       500/resolver
+`
+
+func TestParseNetstatOutput(t *testing.T) {
+	entries := ParseNetstatOutput(netstatFixture)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx     int
+		process string
+		pid     int
+		user    string
+		port    int
+		proto   Protocol
+		state   State
+		remote  string
+	}{
+		{0, "postgres", 200, "0", 5432, TCP, "LISTEN", ""},
+		{1, "node", 300, "1000", 54321, TCP, "ESTABLISHED", "93.184.216.34:443"},
+		{2, "node", 300, "1000", 3000, TCP, "LISTEN", ""},
+		{3, "avahi-daemon", 400, "0", 5353, UDP, "LISTEN", ""},
+		{4, "resolver", 500, "0", 41000, UDP, "ESTABLISHED", "10.0.0.1:53"},
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.Process != tt.process {
+			t.Errorf("[%d] process: got %q, want %q", tt.idx, e.Process, tt.process)
+		}
+		if e.PID != tt.pid {
+			t.Errorf("[%d] pid: got %d, want %d", tt.idx, e.PID, tt.pid)
+		}
+		if e.User != tt.user {
+			t.Errorf("[%d] user: got %q, want %q", tt.idx, e.User, tt.user)
+		}
+		if e.Port != tt.port {
+			t.Errorf("[%d] port: got %d, want %d", tt.idx, e.Port, tt.port)
+		}
+		if e.Protocol != tt.proto {
+			t.Errorf("[%d] protocol: got %q, want %q", tt.idx, e.Protocol, tt.proto)
+		}
+		if e.State != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, e.State, tt.state)
+		}
+		if e.Remote() != tt.remote {
+			t.Errorf("[%d] remote: got %q, want %q", tt.idx, e.Remote(), tt.remote)
+		}
+	}
+}
+
+func TestParseNetstatOutput_EmptyInput(t *testing.T) {
+	if entries := ParseNetstatOutput(""); len(entries) != 0 {
+		t.Errorf("expected 0 entries, got %d", len(entries))
+	}
+}
+
+func TestNetstatScanner_ResolvesUsers(t *testing.T) {
+	s := NewNetstatScanner(&MockCmdRunner{Output: []byte(netstatFixture)})
+
+	entries, err := s.FindByPort(context.Background(), 5432)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].User != "root" {
+		t.Errorf("user: got %q, want root", entries[0].User)
+	}
+}
@@ -1,36 +1,59 @@
 package port
 
 import (
+	"bufio"
+	"bytes"
+	"io"
 	"strconv"
 	"strings"
 )
 
+// maxLsofLine bounds a single line of lsof output. Lines are normally
+// short, but a name field can hold a long Unix socket path.
+const maxLsofLine = 1 << 20
+
+// newLineScanner returns a scanner over the lines of r.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLsofLine)
+	return sc
+}
+
 // ParseLsofOutput parses the columnar output from lsof -iTCP -iUDP -P -n.
 // Each line after the header has fields: COMMAND PID USER FD TYPE DEVICE SIZE/OFF NODE NAME
 func ParseLsofOutput(output string) []PortEntry {
-	lines := strings.Split(output, "\n")
-	if len(lines) < 2 {
-		return nil
+	entries, _ := ParseLsofReader(strings.NewReader(output), nil)
+	return entries
+}
+
+// ParseLsofReader parses columnar lsof output as it is read from r, the
+// same as ParseLsofOutput. Only entries accepted by keep are returned, or
+// all of them if keep is nil.
+func ParseLsofReader(r io.Reader, keep func(PortEntry) bool) ([]PortEntry, error) {
+	sc := newLineScanner(r)
+	if !sc.Scan() { // header
+		return nil, sc.Err()
 	}
 
 	var entries []PortEntry
-	for _, line := range lines[1:] {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
 			continue
 		}
 
-		entry, ok := parseLsofLine(line)
-		if !ok {
+		entry, ok := parseLsofLine(string(line))
+		if !ok || (keep != nil && !keep(entry)) {
 			continue
 		}
 		entries = append(entries, entry)
 	}
-	return entries
+	return entries, sc.Err()
 }
 
 // parseLsofLine parses a single lsof output line into a PortEntry.
 // Format: COMMAND  PID  USER  FD  TYPE  DEVICE  SIZE/OFF  NODE  NAME
+// NAME may be followed by a state in parentheses, e.g. "*:80 (LISTEN)".
 func parseLsofLine(line string) (PortEntry, bool) {
 	fields := strings.Fields(line)
 	if len(fields) < 9 {
@@ -43,20 +66,27 @@ func parseLsofLine(line string) (PortEntry, bool) {
 	}
 
 	proto := parseProtocol(fields[7])
-	port, state := parseNameField(fields[8], proto)
-	if port < 0 {
+	local, remote, state := parseNameField(strings.Join(fields[8:], " "), proto)
+	if local.port < 0 {
 		return PortEntry{}, false
 	}
+	addr, family := bindAddr(local.addr, lsofFamily(fields[4]), v6OnlyUnknown)
+	remoteAddr, remotePort := peerAddr(remote.addr, remote.port)
 
 	return PortEntry{
-		Process:  fields[0],
-		PID:      pid,
-		User:     fields[2],
-		FD:       fields[3],
-		Protocol: proto,
-		Port:     port,
-		State:    state,
-		Command:  fields[0], // will be enriched later via ps
+		Process:    fields[0],
+		PID:        pid,
+		User:       fields[2],
+		FD:         fields[3],
+		Protocol:   proto,
+		Port:       local.port,
+		State:      state,
+		Command:    fields[0], // replaced by the full command line on enrichment
+		LocalAddr:  addr,
+		Family:     family,
+		Zone:       local.zone,
+		RemoteAddr: remoteAddr,
+		RemotePort: remotePort,
 	}, true
 }
 
@@ -69,53 +99,346 @@ func parseProtocol(node string) Protocol {
 	return TCP
 }
 
-// parseNameField extracts the port number and connection state from the NAME field.
+// parseNameField extracts the local and remote endpoints and the connection
+// state from the NAME field.
 // NAME formats:
 //   - "*:8080" or "127.0.0.1:8080" (LISTEN implied)
 //   - "127.0.0.1:8080->127.0.0.1:54321" (ESTABLISHED)
 //   - "*:8080 (LISTEN)" or similar with state in parentheses
+//   - "[::1]:5432" or "[fe80::1%en0]:123" for IPv6
 //
-// For connections with "->", we extract the local port (left side).
-func parseNameField(name string, proto Protocol) (int, string) {
-	state := ""
+// For connections with "->", the left side is the local endpoint and the
+// right side the remote one. The local endpoint has port -1 if the name has
+// no usable port; the remote endpoint is zero when there is no peer.
+func parseNameField(name string, proto Protocol) (endpoint, endpoint, State) {
+	var state State
 
 	// Check for state in parentheses at the end.
 	if idx := strings.LastIndex(name, "("); idx != -1 {
 		closeParen := strings.LastIndex(name, ")")
 		if closeParen > idx {
-			state = name[idx+1 : closeParen]
+			state, _ = ParseState(name[idx+1 : closeParen])
 			name = strings.TrimSpace(name[:idx])
 		}
 	}
 
 	// Split on "->" for established connections.
 	local := name
+	var remote endpoint
 	if idx := strings.Index(name, "->"); idx != -1 {
 		local = name[:idx]
+		if ep, ok := parseEndpoint(name[idx+2:]); ok && ep.port > 0 {
+			remote = ep
+		}
 		if state == "" {
-			state = "ESTABLISHED"
+			state = StateEstablished
 		}
 	}
 
-	// Extract port from local address.
-	portStr := local
-	if idx := strings.LastIndex(local, ":"); idx != -1 {
-		portStr = local[idx+1:]
+	// Handle wildcard or port-only entries.
+	ep, ok := parseEndpoint(local)
+	if !ok || ep.port < 0 {
+		return endpoint{port: -1}, endpoint{}, ""
 	}
 
-	// Handle wildcard or port-only entries.
-	if portStr == "*" {
-		return -1, ""
+	if state == "" {
+		state = StateListen
 	}
 
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return -1, ""
+	return ep, remote, state
+}
+
+// lsofFamily converts the lsof TYPE column to the socket's family.
+func lsofFamily(typ string) Family {
+	switch typ {
+	case "IPv4":
+		return IPv4
+	case "IPv6":
+		return IPv6
 	}
+	return ""
+}
 
-	if state == "" {
-		state = "LISTEN"
+// lsofFieldSpec selects the fields requested with lsof -F: PID, command,
+// UID, login, FD, access mode, type, protocol, name and TCP info.
+const lsofFieldSpec = "pcuLfatPnT"
+
+// ParseLsofFieldOutput parses the tagged output from lsof -F pcuLfatPnT.
+// Each line starts with a field character: a "p" line opens a process set
+// (followed by "c", "u" and "L"), and an "f" line opens a file set within
+// it (followed by "a", "t", "P", "n" and repeated "T" lines such as
+// "TST=LISTEN"). Unlike the column format, command names may contain
+// spaces and are not truncated when lsof is run with +c 0.
+//
+// The User field holds the login name when lsof reports one and the
+// numeric UID otherwise.
+func ParseLsofFieldOutput(output string) []PortEntry {
+	entries, _ := ParseLsofFieldReader(strings.NewReader(output), nil)
+	return entries
+}
+
+// ParseLsofFieldReader parses lsof -F output as it is read from r, the
+// same as ParseLsofFieldOutput. Only entries accepted by keep are
+// returned, or all of them if keep is nil.
+func ParseLsofFieldReader(r io.Reader, keep func(PortEntry) bool) ([]PortEntry, error) {
+	return parseLsofFields(r, (*lsofFile).entry, keep)
+}
+
+// ParseLsofUnixOutput parses lsof -U -F pcuLfatPnT output into Unix socket
+// entries. On Linux the name field holds the path followed by the socket
+// type, e.g. "/run/docker.sock type=STREAM"; unnamed sockets are skipped.
+func ParseLsofUnixOutput(output string) []PortEntry {
+	entries, _ := parseLsofFields(strings.NewReader(output), (*lsofFile).unixEntry, nil)
+	return entries
+}
+
+// parseLsofFields walks lsof -F output and converts each file set with
+// convert, keeping the entries accepted by keep. Values that repeat on
+// every file set, such as the protocol, are not copied, so memory use
+// grows with the number of entries kept rather than the size of the
+// output.
+func parseLsofFields(r io.Reader, convert func(*lsofFile, lsofProcess) (PortEntry, bool), keep func(PortEntry) bool) ([]PortEntry, error) {
+	var (
+		entries []PortEntry
+		proc    lsofProcess
+		file    lsofFile
+		inFile  bool
+	)
+
+	flush := func() {
+		if !inFile {
+			return
+		}
+		if entry, ok := convert(&file, proc); ok && (keep == nil || keep(entry)) {
+			entries = append(entries, entry)
+		}
+		inFile = false
+	}
+
+	sc := newLineScanner(r)
+	for sc.Scan() {
+		line := bytes.TrimRight(sc.Bytes(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+		tag, value := line[0], line[1:]
+
+		switch tag {
+		case 'p':
+			flush()
+			pid, ok := atoiBytes(value)
+			if !ok {
+				pid = -1
+			}
+			proc = lsofProcess{pid: pid}
+		case 'c':
+			proc.command = string(value)
+		case 'u':
+			proc.uid = string(value)
+		case 'L':
+			proc.login = string(value)
+		case 'f':
+			flush()
+			file = lsofFile{fd: internField(value)}
+			inFile = true
+		default:
+			if inFile {
+				file.set(tag, value)
+			}
+		}
+	}
+	flush()
+
+	return entries, sc.Err()
+}
+
+// atoiBytes parses a non-negative decimal number without allocating.
+func atoiBytes(b []byte) (int, bool) {
+	if len(b) == 0 || len(b) > 18 {
+		return 0, false
+	}
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// internField returns the string for a field value, sharing the common
+// ones instead of allocating a copy for every line.
+func internField(b []byte) string {
+	switch string(b) {
+	case "u":
+		return "u"
+	case "r":
+		return "r"
+	case "w":
+		return "w"
+	case "IPv4":
+		return "IPv4"
+	case "IPv6":
+		return "IPv6"
+	case "unix":
+		return "unix"
+	case "TCP":
+		return "TCP"
+	case "UDP":
+		return "UDP"
+	case "LISTEN":
+		return "LISTEN"
+	case "ESTABLISHED":
+		return "ESTABLISHED"
+	}
+	if n, ok := atoiBytes(b); ok && n < len(smallFDs) {
+		return smallFDs[n]
+	}
+	return string(b)
+}
+
+// smallFDs holds the names of the lowest file descriptor numbers.
+var smallFDs = func() []string {
+	fds := make([]string, 256)
+	for i := range fds {
+		fds[i] = strconv.Itoa(i)
+	}
+	return fds
+}()
+
+// lsofProcess holds the process-set fields of lsof -F output.
+type lsofProcess struct {
+	pid     int
+	command string
+	uid     string
+	login   string
+}
+
+// lsofFile holds the file-set fields of lsof -F output.
+type lsofFile struct {
+	fd     string
+	access string
+	typ    string
+	proto  string
+	name   string
+	state  string
+}
+
+// set records a single file-set field.
+func (f *lsofFile) set(tag byte, value []byte) {
+	switch tag {
+	case 'a':
+		f.access = internField(bytes.TrimSpace(value))
+	case 't':
+		f.typ = internField(value)
+	case 'P':
+		f.proto = internField(value)
+	case 'n':
+		f.name = string(value)
+	case 'T':
+		if st, ok := bytes.CutPrefix(value, []byte("ST=")); ok {
+			f.state = internField(st)
+		}
+	}
+}
+
+// entry converts a completed file set into a PortEntry.
+func (f *lsofFile) entry(proc lsofProcess) (PortEntry, bool) {
+	if proc.pid < 0 || f.name == "" {
+		return PortEntry{}, false
+	}
+
+	var proto Protocol
+	switch strings.ToUpper(f.proto) {
+	case "TCP":
+		proto = TCP
+	case "UDP":
+		proto = UDP
+	default:
+		return PortEntry{}, false
+	}
+
+	local, remote, state := parseNameField(f.name, proto)
+	if local.port < 0 {
+		return PortEntry{}, false
+	}
+	if f.state != "" {
+		state, _ = ParseState(f.state)
+	}
+	addr, family := bindAddr(local.addr, lsofFamily(f.typ), v6OnlyUnknown)
+	remoteAddr, remotePort := peerAddr(remote.addr, remote.port)
+
+	return PortEntry{
+		Process:    proc.command,
+		PID:        proc.pid,
+		User:       proc.user(),
+		FD:         f.fd + f.access,
+		Protocol:   proto,
+		Port:       local.port,
+		State:      state,
+		Command:    proc.command,
+		LocalAddr:  addr,
+		Family:     family,
+		Zone:       local.zone,
+		RemoteAddr: remoteAddr,
+		RemotePort: remotePort,
+	}, true
+}
+
+// unixEntry converts a completed unix file set into a PortEntry.
+func (f *lsofFile) unixEntry(proc lsofProcess) (PortEntry, bool) {
+	if proc.pid < 0 || f.typ != "unix" {
+		return PortEntry{}, false
+	}
+
+	path, typ, _ := strings.Cut(f.name, " type=")
+	if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "@") {
+		// Unnamed sockets, or "->0x..." peers on macOS.
+		return PortEntry{}, false
+	}
+
+	state, _ := ParseState(f.state)
+	if f.state == "" {
+		state = StateListen
+	}
+	if typ == "DGRAM" && state == StateClose {
+		state = StateListen
 	}
 
-	return port, state
+	return PortEntry{
+		Process:  proc.command,
+		PID:      proc.pid,
+		User:     proc.user(),
+		FD:       f.fd + f.access,
+		Protocol: Unix,
+		State:    state,
+		Command:  proc.command,
+		Path:     path,
+	}, true
+}
+
+// user returns the login name when lsof reports one and the UID otherwise.
+func (p lsofProcess) user() string {
+	if p.login != "" {
+		return p.login
+	}
+	return p.uid
+}
+
+// parseLsofStream parses lsof output in either the -F field format or
+// the default column format, telling them apart by the first byte.
+func parseLsofStream(r io.Reader, keep func(PortEntry) bool) ([]PortEntry, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] == 'p' {
+		return ParseLsofFieldReader(br, keep)
+	}
+	return ParseLsofReader(br, keep)
 }
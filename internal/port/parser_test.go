@@ -1,6 +1,8 @@
 package port
 
 import (
+	"context"
+	"errors"
 	"testing"
 )
 
@@ -26,7 +28,7 @@ java       3456   zhengda   10u  IPv4 0x1234567894      0t0  TCP *:8080 (LISTEN)
 		user    string
 		port    int
 		proto   Protocol
-		state   string
+		state   State
 	}{
 		{0, "nginx", 1234, "root", 80, TCP, "LISTEN"},
 		{1, "nginx", 1234, "root", 443, TCP, "LISTEN"},
@@ -78,6 +80,9 @@ chrome     1111   zhengda   20u  IPv4 0x1234567890      0t0  TCP 192.168.1.10:54
 	if e.Process != "chrome" {
 		t.Errorf("process: got %q, want chrome", e.Process)
 	}
+	if e.Remote() != "93.184.216.34:443" {
+		t.Errorf("remote: got %q, want 93.184.216.34:443", e.Remote())
+	}
 }
 
 func TestParseLsofOutput_UDP(t *testing.T) {
@@ -117,25 +122,42 @@ func TestParseLsofOutput_HeaderOnly(t *testing.T) {
 
 func TestParseNameField(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    string
-		proto    Protocol
-		wantPort int
-		wantSt   string
+		name       string
+		input      string
+		proto      Protocol
+		wantPort   int
+		wantAddr   string
+		wantZone   string
+		wantRemote string
+		wantRPort  int
+		wantSt     State
 	}{
-		{"listen wildcard", "*:8080", TCP, 8080, "LISTEN"},
-		{"listen localhost", "127.0.0.1:3000", TCP, 3000, "LISTEN"},
-		{"listen with state", "*:443 (LISTEN)", TCP, 443, "LISTEN"},
-		{"established", "192.168.1.10:54321->93.184.216.34:443", TCP, 54321, "ESTABLISHED"},
-		{"wildcard star", "*:*", TCP, -1, ""},
-		{"udp port", "*:5353", UDP, 5353, "LISTEN"},
+		{"listen wildcard", "*:8080", TCP, 8080, "*", "", "", 0, "LISTEN"},
+		{"listen localhost", "127.0.0.1:3000", TCP, 3000, "127.0.0.1", "", "", 0, "LISTEN"},
+		{"listen with state", "*:443 (LISTEN)", TCP, 443, "*", "", "", 0, "LISTEN"},
+		{"established", "192.168.1.10:54321->93.184.216.34:443", TCP, 54321, "192.168.1.10", "", "93.184.216.34", 443, "ESTABLISHED"},
+		{"close wait", "127.0.0.1:5432->127.0.0.1:50000 (CLOSE_WAIT)", TCP, 5432, "127.0.0.1", "", "127.0.0.1", 50000, "CLOSE_WAIT"},
+		{"ipv6 established", "[2001:db8::10]:54321->[2001:db8::1]:5432 (ESTABLISHED)", TCP, 54321, "2001:db8::10", "", "2001:db8::1", 5432, "ESTABLISHED"},
+		{"ipv6 loopback", "[::1]:5432", TCP, 5432, "::1", "", "", 0, "LISTEN"},
+		{"ipv6 zone", "[fe80::1%lo0]:123", UDP, 123, "fe80::1", "lo0", "", 0, "LISTEN"},
+		{"wildcard star", "*:*", TCP, -1, "", "", "", 0, ""},
+		{"udp port", "*:5353", UDP, 5353, "*", "", "", 0, "LISTEN"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			port, state := parseNameField(tt.input, tt.proto)
-			if port != tt.wantPort {
-				t.Errorf("port: got %d, want %d", port, tt.wantPort)
+			local, remote, state := parseNameField(tt.input, tt.proto)
+			if local.port != tt.wantPort {
+				t.Errorf("port: got %d, want %d", local.port, tt.wantPort)
+			}
+			if local.addr != tt.wantAddr {
+				t.Errorf("addr: got %q, want %q", local.addr, tt.wantAddr)
+			}
+			if local.zone != tt.wantZone {
+				t.Errorf("zone: got %q, want %q", local.zone, tt.wantZone)
+			}
+			if remote.addr != tt.wantRemote || remote.port != tt.wantRPort {
+				t.Errorf("remote: got %s:%d, want %s:%d", remote.addr, remote.port, tt.wantRemote, tt.wantRPort)
 			}
 			if state != tt.wantSt {
 				t.Errorf("state: got %q, want %q", state, tt.wantSt)
@@ -143,3 +165,241 @@ func TestParseNameField(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLsofOutput_BindAddress(t *testing.T) {
+	input := `COMMAND     PID      USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME
+postgres   9012 _postgres    9u  IPv4 0x1234567893      0t0  TCP 127.0.0.1:5432 (LISTEN)
+postgres   9012 _postgres   10u  IPv6 0x1234567895      0t0  TCP [::1]:5432 (LISTEN)
+node       5678   zhengda    8u  IPv6 0x1234567892      0t0  TCP *:3000 (LISTEN)
+nginx      1234      root    6u  IPv4 0x1234567890      0t0  TCP *:80 (LISTEN)
+ntpd        321      root    5u  IPv6 0x1234567896      0t0  UDP [fe80:1::1%lo0]:123
+`
+
+	entries := ParseLsofOutput(input)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		addr   string
+		family Family
+		zone   string
+		bind   string
+	}{
+		{"127.0.0.1", IPv4, "", "127.0.0.1"},
+		{"::1", IPv6, "", "::1"},
+		{"*", IPv6, "", "*"}, // lsof cannot tell if it is v6-only
+		{"*", IPv4, "", "*"},
+		{"fe80:1::1", IPv6, "lo0", "fe80:1::1%lo0"},
+	}
+
+	for i, tt := range tests {
+		e := entries[i]
+		if e.LocalAddr != tt.addr {
+			t.Errorf("[%d] addr: got %q, want %q", i, e.LocalAddr, tt.addr)
+		}
+		if e.Family != tt.family {
+			t.Errorf("[%d] family: got %q, want %q", i, e.Family, tt.family)
+		}
+		if e.Zone != tt.zone {
+			t.Errorf("[%d] zone: got %q, want %q", i, e.Zone, tt.zone)
+		}
+		if e.Bind() != tt.bind {
+			t.Errorf("[%d] bind: got %q, want %q", i, e.Bind(), tt.bind)
+		}
+	}
+}
+
+func TestParseLsofFieldOutput(t *testing.T) {
+	input := `p644
+cControlCenter
+u501
+Lzhengda
+f9
+au
+tIPv4
+PTCP
+n*:5000
+TST=LISTEN
+TQR=0
+TQS=0
+f10
+au
+tIPv6
+PTCP
+n*:7000
+TST=LISTEN
+p1111
+cGoogle Chrome Helper
+u501
+Lzhengda
+f20
+au
+tIPv4
+PTCP
+n192.168.1.10:54321->93.184.216.34:443
+TST=ESTABLISHED
+p100
+cmDNSResponder
+u65
+f5
+au
+tIPv4
+PUDP
+n*:5353
+p9012
+cpostgres
+u70
+f9
+au
+tIPv6
+PTCP
+n[::1]:5432
+TST=CLOSE_WAIT
+`
+
+	entries := ParseLsofFieldOutput(input)
+
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx     int
+		process string
+		pid     int
+		user    string
+		fd      string
+		port    int
+		proto   Protocol
+		state   State
+	}{
+		{0, "ControlCenter", 644, "zhengda", "9u", 5000, TCP, "LISTEN"},
+		{1, "ControlCenter", 644, "zhengda", "10u", 7000, TCP, "LISTEN"},
+		{2, "Google Chrome Helper", 1111, "zhengda", "20u", 54321, TCP, "ESTABLISHED"},
+		{3, "mDNSResponder", 100, "65", "5u", 5353, UDP, "LISTEN"},
+		{4, "postgres", 9012, "70", "9u", 5432, TCP, "CLOSE_WAIT"},
+	}
+
+	wantBinds := []struct {
+		addr   string
+		family Family
+	}{
+		{"*", IPv4},
+		{"*", IPv6},
+		{"192.168.1.10", IPv4},
+		{"*", IPv4},
+		{"::1", IPv6},
+	}
+	for i, w := range wantBinds {
+		if entries[i].LocalAddr != w.addr || entries[i].Family != w.family {
+			t.Errorf("[%d] bind: got %s (%s), want %s (%s)",
+				i, entries[i].LocalAddr, entries[i].Family, w.addr, w.family)
+		}
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.Process != tt.process {
+			t.Errorf("[%d] process: got %q, want %q", tt.idx, e.Process, tt.process)
+		}
+		if e.Command != tt.process {
+			t.Errorf("[%d] command: got %q, want %q", tt.idx, e.Command, tt.process)
+		}
+		if e.PID != tt.pid {
+			t.Errorf("[%d] pid: got %d, want %d", tt.idx, e.PID, tt.pid)
+		}
+		if e.User != tt.user {
+			t.Errorf("[%d] user: got %q, want %q", tt.idx, e.User, tt.user)
+		}
+		if e.FD != tt.fd {
+			t.Errorf("[%d] fd: got %q, want %q", tt.idx, e.FD, tt.fd)
+		}
+		if e.Port != tt.port {
+			t.Errorf("[%d] port: got %d, want %d", tt.idx, e.Port, tt.port)
+		}
+		if e.Protocol != tt.proto {
+			t.Errorf("[%d] protocol: got %q, want %q", tt.idx, e.Protocol, tt.proto)
+		}
+		if e.State != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, e.State, tt.state)
+		}
+	}
+}
+
+func TestParseLsofFieldOutput_SkipsNonInet(t *testing.T) {
+	input := `p1
+claunchd
+u0
+Lroot
+f3
+au
+tunix
+n/var/run/syslog
+f4
+au
+tIPv4
+PTCP
+n*:*
+`
+
+	entries := ParseLsofFieldOutput(input)
+	if len(entries) != 0 {
+		t.Errorf("expected 0 entries, got %d", len(entries))
+	}
+}
+
+func TestParseLsofFieldOutput_EmptyInput(t *testing.T) {
+	entries := ParseLsofFieldOutput("")
+	if len(entries) != 0 {
+		t.Errorf("expected 0 entries, got %d", len(entries))
+	}
+}
+
+func TestLsofScanner_FieldOutput(t *testing.T) {
+	runner := &MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			"lsof +c 0 -F pcuLfatPnT -i:3000 -P -n": {Output: []byte("p5678\ncnode\nu0\nf8\nau\ntIPv6\nPTCP\nn*:3000\nTST=LISTEN\n")},
+		},
+	}
+	s := NewLsofScanner(runner)
+
+	entries, err := s.FindByPort(context.Background(), 3000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].User != "root" {
+		t.Errorf("user: got %q, want root", entries[0].User)
+	}
+}
+
+func TestLsofScanner_ColumnFallback(t *testing.T) {
+	runner := &MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			"lsof +c 0 -F pcuLfatPnT -iTCP -iUDP -sTCP:LISTEN -P -n": {Err: errors.New("unsupported option")},
+			"lsof -iTCP -iUDP -sTCP:LISTEN -P -n": {Output: []byte(`COMMAND     PID      USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME
+nginx      1234      root    6u  IPv4 0x1234567890      0t0  TCP *:80 (LISTEN)
+`)},
+		},
+	}
+	s := NewLsofScanner(runner)
+
+	entries, err := s.ListPorts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Process != "nginx" || entries[0].Port != 80 {
+		t.Fatalf("expected nginx on port 80, got %v", entries)
+	}
+}
+
+func TestLsofScanner_Error(t *testing.T) {
+	s := NewLsofScanner(&MockCmdRunner{Err: errors.New("lsof: not found")})
+
+	if _, err := s.ListPorts(context.Background()); err == nil {
+		t.Fatal("expected error when lsof fails")
+	}
+}
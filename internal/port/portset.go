@@ -0,0 +1,134 @@
+package port
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// portRange is an inclusive range of ports.
+type portRange struct {
+	lo, hi int
+}
+
+func (r portRange) String() string {
+	if r.lo == r.hi {
+		return strconv.Itoa(r.lo)
+	}
+	return fmt.Sprintf("%d-%d", r.lo, r.hi)
+}
+
+// PortSet is a set of ports given as a comma-separated list of ports,
+// ranges and service names, where a leading "!" excludes, e.g.
+// "3000-3999,8080,postgres,!5432". A set of nothing but exclusions holds
+// every other port.
+type PortSet struct {
+	include []portRange
+	exclude []portRange
+}
+
+// ParsePortSet parses a port set. Service names are looked up with
+// services, which may be nil to accept numbers only.
+func ParsePortSet(spec string, services *ServiceResolver) (*PortSet, error) {
+	s := &PortSet{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		negate := strings.HasPrefix(item, "!")
+		r, err := parsePortRange(strings.TrimSpace(strings.TrimPrefix(item, "!")), services)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			s.exclude = append(s.exclude, r)
+		} else {
+			s.include = append(s.include, r)
+		}
+	}
+	if len(s.include) == 0 && len(s.exclude) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return s, nil
+}
+
+// parsePortRange parses a port, a service name or a "lo-hi" range. Names
+// are tried before ranges, since some contain a dash, like "http-alt".
+func parsePortRange(item string, services *ServiceResolver) (portRange, error) {
+	if item == "" {
+		return portRange{}, fmt.Errorf("missing port after \"!\"")
+	}
+	if n, err := services.ParsePort(item); err == nil {
+		return portRange{n, n}, nil
+	}
+	loStr, hiStr, ok := strings.Cut(item, "-")
+	if !ok {
+		return portRange{}, fmt.Errorf("unknown port or service name %q", item)
+	}
+	lo, err := services.ParsePort(loStr)
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid range %q: %w", item, err)
+	}
+	hi, err := services.ParsePort(hiStr)
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid range %q: %w", item, err)
+	}
+	if lo > hi {
+		return portRange{}, fmt.Errorf("invalid range %q: %d is greater than %d", item, lo, hi)
+	}
+	return portRange{lo, hi}, nil
+}
+
+// Match reports whether the port is in the set. A nil set matches every
+// port.
+func (s *PortSet) Match(port int) bool {
+	if s == nil {
+		return true
+	}
+	for _, r := range s.exclude {
+		if port >= r.lo && port <= r.hi {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return port > 0
+	}
+	for _, r := range s.include {
+		if port >= r.lo && port <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// Single returns the port of a set that holds exactly one port, which can
+// be scanned for directly.
+func (s *PortSet) Single() (int, bool) {
+	if s == nil || len(s.include) == 0 {
+		return 0, false
+	}
+	p := s.include[0].lo
+	for _, r := range s.include {
+		if r.lo != p || r.hi != p {
+			return 0, false
+		}
+	}
+	if !s.Match(p) {
+		return 0, false
+	}
+	return p, true
+}
+
+// String returns the set in the syntax ParsePortSet accepts, with service
+// names replaced by their ports.
+func (s *PortSet) String() string {
+	var parts []string
+	for _, r := range s.include {
+		parts = append(parts, r.String())
+	}
+	for _, r := range s.exclude {
+		parts = append(parts, "!"+r.String())
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,81 @@
+package port
+
+import "testing"
+
+func TestParsePortSet(t *testing.T) {
+	services := newServiceResolver("/nonexistent", map[int]string{8000: "api"})
+
+	tests := []struct {
+		spec    string
+		match   []int
+		noMatch []int
+		str     string
+	}{
+		{"3000", []int{3000}, []int{3001, 0}, "3000"},
+		{"3000-3999,8080", []int{3000, 3500, 3999, 8080}, []int{2999, 4000, 8081}, "3000-3999,8080"},
+		{"3000-3999,!3306", []int{3000, 3305, 3307}, []int{3306, 4000}, "3000-3999,!3306"},
+		{"!22,!5432", []int{80, 3000}, []int{22, 5432, 0}, "!22,!5432"},
+		{"api, postgresql", []int{8000, 5432}, []int{8080}, "8000,5432"},
+		{"http-alt", []int{8080}, []int{8081}, "8080"},
+		{"redis-6390", []int{6379, 6390}, []int{6378, 6391}, "6379-6390"},
+		{"3000-3100,!3050-3060", []int{3049, 3061}, []int{3050, 3055, 3060}, "3000-3100,!3050-3060"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParsePortSet(tt.spec, services)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, p := range tt.match {
+				if !s.Match(p) {
+					t.Errorf("expected %d to match", p)
+				}
+			}
+			for _, p := range tt.noMatch {
+				if s.Match(p) {
+					t.Errorf("expected %d not to match", p)
+				}
+			}
+			if got := s.String(); got != tt.str {
+				t.Errorf("String: got %q, want %q", got, tt.str)
+			}
+		})
+	}
+}
+
+func TestParsePortSet_Errors(t *testing.T) {
+	for _, spec := range []string{"", ",", "0", "70000", "4000-3000", "3000-", "!", "nosuch", "3000-nosuch"} {
+		if _, err := ParsePortSet(spec, nil); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestPortSet_Single(t *testing.T) {
+	tests := []struct {
+		spec string
+		want int
+	}{
+		{"3000", 3000},
+		{"3000,3000", 3000},
+		{"3000-3001", 0},
+		{"3000,3001", 0},
+		{"!3000", 0},
+		{"3000,!3000", 0},
+	}
+	for _, tt := range tests {
+		s, err := ParsePortSet(tt.spec, nil)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.spec, err)
+		}
+		got, ok := s.Single()
+		if ok != (tt.want != 0) || got != tt.want {
+			t.Errorf("%q: got %d, %v; want %d", tt.spec, got, ok, tt.want)
+		}
+	}
+
+	var s *PortSet
+	if !s.Match(80) {
+		t.Error("expected a nil set to match every port")
+	}
+}
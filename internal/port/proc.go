@@ -0,0 +1,268 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procNetFiles lists the /proc/net tables read by ProcScanner.
+var procNetFiles = []struct {
+	name  string
+	proto Protocol
+}{
+	{"tcp", TCP},
+	{"tcp6", TCP},
+	{"udp", UDP},
+	{"udp6", UDP},
+}
+
+// ProcScanner implements Scanner on Linux by reading /proc/net and
+// resolving socket inodes to processes through /proc/<pid>/fd.
+type ProcScanner struct {
+	root  string
+	users userCache
+	procs processCache
+}
+
+// NewProcScanner creates a new scanner that reads the proc filesystem
+// mounted at root. An empty root means "/proc".
+func NewProcScanner(root string) *ProcScanner {
+	if root == "" {
+		root = "/proc"
+	}
+	return &ProcScanner{root: root}
+}
+
+// ListPorts returns all listening TCP ports and all UDP sockets.
+func (s *ProcScanner) ListPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.scan(ctx, func(sock socketInfo) bool {
+		return sock.proto == UDP || sock.state == StateListen
+	})
+}
+
+// ListAllPorts returns all connections including ESTABLISHED.
+func (s *ProcScanner) ListAllPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.scan(ctx, nil)
+}
+
+// FindByPort returns all entries matching the given port number.
+func (s *ProcScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
+	return s.scan(ctx, func(sock socketInfo) bool {
+		return sock.localPort == port
+	})
+}
+
+// FindByProcess returns all entries matching the given process name.
+func (s *ProcScanner) FindByProcess(ctx context.Context, name string) ([]PortEntry, error) {
+	entries, err := s.ListPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchProcess(entries, name), nil
+}
+
+// scan reads the socket tables, keeps the sockets accepted by keep (all of
+// them if keep is nil), and joins them with their owning processes.
+func (s *ProcScanner) scan(ctx context.Context, keep func(socketInfo) bool) ([]PortEntry, error) {
+	socks, err := s.readSockets(keep)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.joinOwners(ctx, socks)
+}
+
+// readSockets parses the socket tables of every visible network namespace
+// into a map keyed by inode. Socket inodes are unique across namespaces.
+func (s *ProcScanner) readSockets(keep func(socketInfo) bool) (map[uint64]socketInfo, error) {
+	socks := make(map[uint64]socketInfo)
+	dirs := s.netnsDirs()
+	if !readNetTables(dirs[0], keep, socks) {
+		return nil, fmt.Errorf("failed to read socket tables from %s", dirs[0].path)
+	}
+	for _, dir := range dirs[1:] {
+		// The process may have exited since the namespaces were listed.
+		readNetTables(dir, keep, socks)
+	}
+	return socks, nil
+}
+
+// readNetTables parses the TCP and UDP tables in one net directory and
+// reports whether any of them could be read.
+func readNetTables(dir netnsDir, keep func(socketInfo) bool, socks map[uint64]socketInfo) bool {
+	found := false
+	for _, f := range procNetFiles {
+		data, err := os.ReadFile(filepath.Join(dir.path, f.name))
+		if err != nil {
+			// tcp6/udp6 are absent when IPv6 is disabled.
+			continue
+		}
+		found = true
+		for _, sock := range parseProcNet(string(data), f.proto) {
+			if keep != nil && !keep(sock) {
+				continue
+			}
+			sock.netns = dir.netns
+			socks[sock.inode] = sock
+		}
+	}
+	return found
+}
+
+// joinOwners walks /proc/<pid>/fd and emits one PortEntry for every file
+// descriptor that refers to one of the given sockets, with the owners'
+// full command lines, and builds the process table of the scan. It stops
+// when ctx is done. Processes owned by other users are skipped when
+// their fd directory is not readable, the same as lsof without root.
+func (s *ProcScanner) joinOwners(ctx context.Context, socks map[uint64]socketInfo) ([]PortEntry, error) {
+	var entries []PortEntry
+	if len(socks) == 0 {
+		return entries, nil
+	}
+
+	pids := s.pids()
+	for _, pid := range pids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fdDir := filepath.Join(s.root, strconv.Itoa(pid), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		name := ""
+		for _, fd := range sortedFDs(fds) {
+			link, err := os.Readlink(filepath.Join(fdDir, strconv.Itoa(fd)))
+			if err != nil {
+				continue
+			}
+			inode, ok := parseSocketLink(link)
+			if !ok {
+				continue
+			}
+			sock, ok := socks[inode]
+			if !ok {
+				continue
+			}
+
+			if name == "" {
+				name = s.processName(pid)
+			}
+			entries = append(entries, PortEntry{
+				Process:    name,
+				PID:        pid,
+				User:       s.users.name(sock.uid),
+				FD:         strconv.Itoa(fd) + "u",
+				Protocol:   sock.proto,
+				Port:       sock.localPort,
+				State:      sock.state,
+				Command:    name,
+				LocalAddr:  sock.localAddr,
+				Family:     sock.family,
+				Zone:       sock.zone,
+				RemoteAddr: sock.remoteAddr,
+				RemotePort: sock.remotePort,
+				Path:       sock.path,
+				NetNS:      sock.netns,
+			})
+		}
+	}
+	s.procs.enrichFromProc(ctx, s.root, pids, entries)
+	return entries, nil
+}
+
+// pids returns the numeric directory names under the proc root, sorted.
+func (s *ProcScanner) pids() []int {
+	dirs, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+// processName reads the short command name from /proc/<pid>/comm.
+func (s *ProcScanner) processName(pid int) string {
+	data, err := os.ReadFile(filepath.Join(s.root, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// processUser returns the name of the real user running pid, or "" if it
+// cannot be read.
+func (s *ProcScanner) processUser(pid int) string {
+	uid, ok := procUID(s.root, pid)
+	if !ok {
+		return ""
+	}
+	return s.users.name(uid)
+}
+
+// procUID reads the real UID of pid from the "Uid:" line of
+// <root>/<pid>/status.
+func procUID(root string, pid int) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		rest, ok := strings.CutPrefix(line, "Uid:")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return 0, false
+		}
+		uid, err := strconv.Atoi(fields[0])
+		return uid, err == nil
+	}
+	return 0, false
+}
+
+// sortedFDs returns the numeric entries of an fd directory in order.
+func sortedFDs(dirs []os.DirEntry) []int {
+	fds := make([]int, 0, len(dirs))
+	for _, d := range dirs {
+		fd, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		fds = append(fds, fd)
+	}
+	sort.Ints(fds)
+	return fds
+}
+
+// parseSocketLink extracts the inode from an fd link like "socket:[12345]".
+func parseSocketLink(link string) (uint64, bool) {
+	if !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return inode, true
+}
@@ -0,0 +1,305 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const procTCPFixture = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0A01A8C0:D431 22D8B85D:01BB 01 00000000:00000000 02:00000307 00000000     0        0 1003 2 0000000000000000 20 4 10 15 -1
+   3: 0100007F:1538 0100007F:D432 08 00000000:00000000 00:00000000 00000000     0        0 1004 2 0000000000000000 20 4 0 18 -1
+`
+
+const procTCP6Fixture = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0BB8 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000 100 0 0 10 0
+`
+
+const procUDPFixture = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:14E9 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 3001 2 0000000000000000 0
+`
+
+func TestParseProcNet(t *testing.T) {
+	socks := parseProcNet(procTCPFixture, TCP)
+	if len(socks) != 4 {
+		t.Fatalf("expected 4 sockets, got %d", len(socks))
+	}
+
+	tests := []struct {
+		idx        int
+		localPort  int
+		remotePort int
+		state      State
+		inode      uint64
+	}{
+		{0, 8080, 0, "LISTEN", 1001},
+		{1, 5432, 0, "LISTEN", 1002},
+		{2, 54321, 443, "ESTABLISHED", 1003},
+		{3, 5432, 54322, "CLOSE_WAIT", 1004},
+	}
+
+	for _, tt := range tests {
+		s := socks[tt.idx]
+		if s.localPort != tt.localPort {
+			t.Errorf("[%d] local port: got %d, want %d", tt.idx, s.localPort, tt.localPort)
+		}
+		if s.remotePort != tt.remotePort {
+			t.Errorf("[%d] remote port: got %d, want %d", tt.idx, s.remotePort, tt.remotePort)
+		}
+		if s.state != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, s.state, tt.state)
+		}
+		if s.inode != tt.inode {
+			t.Errorf("[%d] inode: got %d, want %d", tt.idx, s.inode, tt.inode)
+		}
+	}
+}
+
+func TestParseProcNet_UDP(t *testing.T) {
+	socks := parseProcNet(procUDPFixture, UDP)
+	if len(socks) != 1 {
+		t.Fatalf("expected 1 socket, got %d", len(socks))
+	}
+	if socks[0].localPort != 5353 {
+		t.Errorf("port: got %d, want 5353", socks[0].localPort)
+	}
+	if socks[0].state != "LISTEN" {
+		t.Errorf("state: got %q, want LISTEN", socks[0].state)
+	}
+}
+
+func TestParseProcNet_EmptyInput(t *testing.T) {
+	if socks := parseProcNet("", TCP); len(socks) != 0 {
+		t.Errorf("expected 0 sockets, got %d", len(socks))
+	}
+}
+
+func TestParseProcAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantIP   string
+		wantPort int
+		wantOK   bool
+	}{
+		{"ipv4 loopback", "0100007F:1538", "127.0.0.1", 5432, true},
+		{"ipv4 any", "00000000:1F90", "0.0.0.0", 8080, true},
+		{"ipv6 any", "00000000000000000000000000000000:0BB8", "::", 3000, true},
+		{"ipv6 loopback", "00000000000000000000000001000000:0016", "::1", 22, true},
+		{"no port", "0100007F", "", 0, false},
+		{"bad hex", "ZZ00007F:1538", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, port, ok := parseProcAddr(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("ok: got %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got := net.IP(ip).String(); got != tt.wantIP {
+				t.Errorf("ip: got %s, want %s", got, tt.wantIP)
+			}
+			if port != tt.wantPort {
+				t.Errorf("port: got %d, want %d", port, tt.wantPort)
+			}
+		})
+	}
+}
+
+const procUnixFixture = `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01  4001 /run/postgresql/.s.PGSQL.5432
+0000000000000000: 00000003 00000000 00000000 0001 03  4002 /run/postgresql/.s.PGSQL.5432
+0000000000000000: 00000002 00000000 00000000 0002 01  4003 /run/my app/notify.sock
+0000000000000000: 00000002 00000000 00010000 0001 01  4004 @/tmp/.X11-unix/X0
+0000000000000000: 00000003 00000000 00000000 0001 03  4005
+`
+
+// writeProcFixture builds a fake proc tree with the socket tables above and
+// the processes holding them open.
+func writeProcFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	netDir := filepath.Join(root, "net")
+	if err := os.MkdirAll(netDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"tcp":  procTCPFixture,
+		"tcp6": procTCP6Fixture,
+		"udp":  procUDPFixture,
+		"unix": procUnixFixture,
+	} {
+		if err := os.WriteFile(filepath.Join(netDir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	procs := []struct {
+		pid  int
+		comm string
+		uid  int
+		fds  map[int]string
+	}{
+		{100, "nginx", 0, map[int]string{0: "/dev/null", 6: "socket:[1001]"}},
+		{200, "postgres", 999, map[int]string{5: "socket:[1002]", 7: "socket:[1004]", 8: "socket:[4001]", 9: "socket:[4002]"}},
+		{300, "node", 1000, map[int]string{20: "socket:[2001]", 21: "socket:[1003]", 22: "socket:[4003]", 23: "socket:[4005]"}},
+		{400, "avahi-daemon", 0, map[int]string{12: "socket:[3001]"}},
+		{500, "Xorg", 0, map[int]string{3: "socket:[4004]"}},
+	}
+	for _, p := range procs {
+		pidDir := filepath.Join(root, strconv.Itoa(p.pid))
+		fdDir := filepath.Join(pidDir, "fd")
+		if err := os.MkdirAll(fdDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pidDir, "comm"), []byte(p.comm+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		status := fmt.Sprintf("Name:\t%s\nUid:\t%d\t%d\t%d\t%d\n", p.comm, p.uid, p.uid, p.uid, p.uid)
+		if err := os.WriteFile(filepath.Join(pidDir, "status"), []byte(status), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for fd, target := range p.fds {
+			if err := os.Symlink(target, filepath.Join(fdDir, strconv.Itoa(fd))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+func TestProcScanner_ListPorts(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := s.ListPorts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx     int
+		process string
+		pid     int
+		port    int
+		proto   Protocol
+		fd      string
+		addr    string
+		family  Family
+	}{
+		{0, "nginx", 100, 8080, TCP, "6u", "*", IPv4},
+		{1, "postgres", 200, 5432, TCP, "5u", "127.0.0.1", IPv4},
+		{2, "node", 300, 3000, TCP, "20u", "*", IPv6},
+		{3, "avahi-daemon", 400, 5353, UDP, "12u", "*", IPv4},
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.Process != tt.process {
+			t.Errorf("[%d] process: got %q, want %q", tt.idx, e.Process, tt.process)
+		}
+		if e.PID != tt.pid {
+			t.Errorf("[%d] pid: got %d, want %d", tt.idx, e.PID, tt.pid)
+		}
+		if e.Port != tt.port {
+			t.Errorf("[%d] port: got %d, want %d", tt.idx, e.Port, tt.port)
+		}
+		if e.Protocol != tt.proto {
+			t.Errorf("[%d] protocol: got %q, want %q", tt.idx, e.Protocol, tt.proto)
+		}
+		if e.FD != tt.fd {
+			t.Errorf("[%d] fd: got %q, want %q", tt.idx, e.FD, tt.fd)
+		}
+		if e.State != "LISTEN" {
+			t.Errorf("[%d] state: got %q, want LISTEN", tt.idx, e.State)
+		}
+		if e.User != "root" {
+			t.Errorf("[%d] user: got %q, want root", tt.idx, e.User)
+		}
+		if e.LocalAddr != tt.addr || e.Family != tt.family {
+			t.Errorf("[%d] bind: got %s (%s), want %s (%s)", tt.idx, e.LocalAddr, e.Family, tt.addr, tt.family)
+		}
+	}
+}
+
+func TestProcScanner_ListAllPorts(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := s.ListAllPorts(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d", len(entries))
+	}
+
+	states := make(map[int]State)
+	remotes := make(map[int]string)
+	for _, e := range entries {
+		if e.State != "LISTEN" {
+			states[e.Port] = e.State
+			remotes[e.Port] = e.Remote()
+		}
+	}
+	if states[54321] != "ESTABLISHED" {
+		t.Errorf("port 54321 state: got %q, want ESTABLISHED", states[54321])
+	}
+	if states[5432] != "CLOSE_WAIT" {
+		t.Errorf("port 5432 state: got %q, want CLOSE_WAIT", states[5432])
+	}
+	if remotes[54321] != "93.184.216.34:443" {
+		t.Errorf("port 54321 remote: got %q, want 93.184.216.34:443", remotes[54321])
+	}
+	if remotes[5432] != "127.0.0.1:54322" {
+		t.Errorf("port 5432 remote: got %q, want 127.0.0.1:54322", remotes[5432])
+	}
+}
+
+func TestProcScanner_FindByPort(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := s.FindByPort(context.Background(), 5432)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.PID != 200 || e.Port != 5432 {
+			t.Errorf("unexpected entry: %s", e)
+		}
+	}
+}
+
+func TestProcScanner_FindByProcess(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := s.FindByProcess(context.Background(), "NODE")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Port != 3000 {
+		t.Fatalf("expected node on port 3000, got %v", entries)
+	}
+}
+
+func TestProcScanner_MissingRoot(t *testing.T) {
+	s := NewProcScanner(filepath.Join(t.TempDir(), "missing"))
+
+	if _, err := s.ListPorts(context.Background()); err == nil {
+		t.Fatal("expected error for missing proc root")
+	}
+}
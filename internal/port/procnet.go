@@ -0,0 +1,161 @@
+package port
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// socketInfo is a kernel socket record before it has been joined with
+// the processes that hold it open.
+type socketInfo struct {
+	proto      Protocol
+	family     Family
+	localAddr  string // normalized bind address, "*" for wildcards
+	zone       string // bound interface, if known
+	localPort  int
+	remoteAddr string // peer address, "" if not connected
+	remotePort int
+	state      State
+	uid        int
+	inode      uint64
+	path       string // Unix socket path
+	netns      uint64 // network namespace inode, 0 if unknown
+}
+
+// tcpStates maps the kernel's numeric TCP states (include/net/tcp_states.h)
+// to States.
+var tcpStates = map[int]State{
+	1:  StateEstablished,
+	2:  StateSynSent,
+	3:  StateSynRecv,
+	4:  StateFinWait1,
+	5:  StateFinWait2,
+	6:  StateTimeWait,
+	7:  StateClose,
+	8:  StateCloseWait,
+	9:  StateLastAck,
+	10: StateListen,
+	11: StateClosing,
+}
+
+// kernelStateName converts a numeric kernel socket state to a State.
+// UDP sockets have no real state: unconnected ones are reported as LISTEN
+// and connected ones as ESTABLISHED, matching how lsof output is parsed.
+func kernelStateName(proto Protocol, st int, remotePort int) State {
+	if proto == UDP {
+		return udpState(remotePort)
+	}
+	if name, ok := tcpStates[st]; ok {
+		return name
+	}
+	return StateUnknown
+}
+
+// parseProcNet parses the contents of /proc/net/{tcp,tcp6,udp,udp6}.
+// Each line after the header has fields:
+// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...
+func parseProcNet(output string, proto Protocol) []socketInfo {
+	lines := strings.Split(output, "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	var socks []socketInfo
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sock, ok := parseProcNetLine(line, proto)
+		if !ok {
+			continue
+		}
+		socks = append(socks, sock)
+	}
+	return socks
+}
+
+// parseProcNetLine parses a single /proc/net socket line.
+func parseProcNetLine(line string, proto Protocol) (socketInfo, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 10 {
+		return socketInfo{}, false
+	}
+
+	localIP, localPort, ok := parseProcAddr(fields[1])
+	if !ok {
+		return socketInfo{}, false
+	}
+	remoteIP, remotePort, ok := parseProcAddr(fields[2])
+	if !ok {
+		return socketInfo{}, false
+	}
+
+	st, err := strconv.ParseInt(fields[3], 16, 32)
+	if err != nil {
+		return socketInfo{}, false
+	}
+
+	uid, err := strconv.Atoi(fields[7])
+	if err != nil {
+		return socketInfo{}, false
+	}
+
+	inode, err := strconv.ParseUint(fields[9], 10, 64)
+	if err != nil {
+		return socketInfo{}, false
+	}
+
+	family := IPv4
+	if len(localIP) == net.IPv6len {
+		family = IPv6
+	}
+	addr, family := bindAddr(net.IP(localIP).String(), family, v6OnlyUnknown)
+	remoteAddr, _ := peerAddr(net.IP(remoteIP).String(), remotePort)
+
+	return socketInfo{
+		proto:      proto,
+		family:     family,
+		localAddr:  addr,
+		localPort:  localPort,
+		remoteAddr: remoteAddr,
+		remotePort: remotePort,
+		state:      kernelStateName(proto, int(st), remotePort),
+		uid:        uid,
+		inode:      inode,
+	}, true
+}
+
+// parseProcAddr decodes an "ADDR:PORT" pair from /proc/net. The address is
+// hex-encoded in 32-bit words stored in host byte order; the port is
+// hex-encoded in network order.
+func parseProcAddr(s string) ([]byte, int, bool) {
+	idx := strings.LastIndex(s, ":")
+	if idx == -1 {
+		return nil, 0, false
+	}
+
+	raw, err := hex.DecodeString(s[:idx])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, false
+	}
+
+	// Each 32-bit word was printed as a host-order integer, so re-encode
+	// it to recover the bytes in network order.
+	ip := make([]byte, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		word := binary.BigEndian.Uint32(raw[i : i+4])
+		binary.NativeEndian.PutUint32(ip[i:i+4], word)
+	}
+
+	port, err := strconv.ParseUint(s[idx+1:], 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	return ip, int(port), true
+}
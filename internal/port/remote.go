@@ -0,0 +1,97 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostLookup resolves a host name to its addresses, like
+// net.Resolver.LookupHost.
+type HostLookup func(ctx context.Context, host string) ([]string, error)
+
+// RemoteFilter matches entries by the peer they are connected to.
+type RemoteFilter struct {
+	Host  string   // host as given, "" to match any peer
+	Port  int      // peer port, 0 to match any port
+	addrs []net.IP // resolved addresses of Host
+}
+
+// ParseRemoteFilter parses a "host", "host:port", "[v6]:port" or ":port"
+// spec. Host names are resolved with lookup so a filter for
+// "db.staging.internal" matches connections to any of its addresses.
+func ParseRemoteFilter(ctx context.Context, spec string, lookup HostLookup) (*RemoteFilter, error) {
+	host, portStr := spec, ""
+	if h, p, err := net.SplitHostPort(spec); err == nil {
+		host, portStr = h, p
+	} else if strings.HasPrefix(spec, "[") && strings.HasSuffix(spec, "]") {
+		host = spec[1 : len(spec)-1]
+	}
+
+	f := &RemoteFilter{Host: host}
+	if portStr != "" {
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid remote port %q", portStr)
+		}
+		f.Port = port
+	}
+
+	if host == "" {
+		if f.Port == 0 {
+			return nil, fmt.Errorf("invalid remote %q: expected host[:port]", spec)
+		}
+		return f, nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		f.addrs = []net.IP{ip}
+		return f, nil
+	}
+
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil {
+			f.addrs = append(f.addrs, ip)
+		}
+	}
+	if len(f.addrs) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+	return f, nil
+}
+
+// Match reports whether the entry is connected to the filter's peer.
+// Entries without a remote endpoint never match.
+func (f *RemoteFilter) Match(e PortEntry) bool {
+	if e.RemoteAddr == "" {
+		return false
+	}
+	if f.Port != 0 && e.RemotePort != f.Port {
+		return false
+	}
+	if len(f.addrs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(e.RemoteAddr)
+	for _, a := range f.addrs {
+		if a.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the filter in host:port form.
+func (f *RemoteFilter) String() string {
+	if f.Port == 0 {
+		return f.Host
+	}
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.Port))
+}
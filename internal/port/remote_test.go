@@ -0,0 +1,103 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeLookup resolves a fixed set of host names.
+func fakeLookup(_ context.Context, host string) ([]string, error) {
+	switch host {
+	case "db.staging":
+		return []string{"10.0.5.20", "fd00::20"}, nil
+	case "localhost":
+		return []string{"127.0.0.1", "::1"}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestParseRemoteFilter(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"10.0.5.20", "10.0.5.20", 0, false},
+		{"10.0.5.20:5432", "10.0.5.20", 5432, false},
+		{"db.staging:5432", "db.staging", 5432, false},
+		{"[fd00::20]:5432", "fd00::20", 5432, false},
+		{"[fd00::20]", "fd00::20", 0, false},
+		{"fd00::20", "fd00::20", 0, false},
+		{":443", "", 443, false},
+		{"db.staging:http", "", 0, true},
+		{"10.0.5.20:70000", "", 0, true},
+		{"unknown.host", "", 0, true},
+		{"", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, err := ParseRemoteFilter(context.Background(), tt.spec, fakeLookup)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.Host != tt.wantHost || f.Port != tt.wantPort {
+				t.Errorf("got %s:%d, want %s:%d", f.Host, f.Port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestRemoteFilter_Match(t *testing.T) {
+	entries := []PortEntry{
+		{Port: 54321, RemoteAddr: "10.0.5.20", RemotePort: 5432},
+		{Port: 54322, RemoteAddr: "fd00::20", RemotePort: 5432},
+		{Port: 54323, RemoteAddr: "10.0.5.20", RemotePort: 6379},
+		{Port: 54324, RemoteAddr: "93.184.216.34", RemotePort: 443},
+		{Port: 5432, LocalAddr: "*"},
+	}
+
+	tests := []struct {
+		spec      string
+		wantPorts []int
+	}{
+		{"db.staging:5432", []int{54321, 54322}},
+		{"db.staging", []int{54321, 54322, 54323}},
+		{"10.0.5.20", []int{54321, 54323}},
+		{":443", []int{54324}},
+		{"localhost", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, err := ParseRemoteFilter(context.Background(), tt.spec, fakeLookup)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []int
+			for _, e := range entries {
+				if f.Match(e) {
+					got = append(got, e.Port)
+				}
+			}
+			if len(got) != len(tt.wantPorts) {
+				t.Fatalf("got ports %v, want %v", got, tt.wantPorts)
+			}
+			for i := range got {
+				if got[i] != tt.wantPorts[i] {
+					t.Errorf("got ports %v, want %v", got, tt.wantPorts)
+					break
+				}
+			}
+		})
+	}
+}
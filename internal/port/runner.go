@@ -1,21 +1,98 @@
 package port
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os/exec"
 	"strings"
+	"time"
 )
 
+// StreamCmdRunner is implemented by runners that can hand a command's
+// stdout to the caller while the command is still running, so that large
+// outputs are parsed without being buffered in full.
+type StreamCmdRunner interface {
+	CmdRunner
+	// Stream runs a command and calls fn with its stdout. If fn returns
+	// an error the command is killed and that error is returned;
+	// otherwise the command's own error, if any, is returned once it
+	// exits.
+	Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error
+}
+
+// streamOutput runs a command with runner and calls fn with its stdout,
+// streaming it when runner supports that and buffering it otherwise.
+func streamOutput(ctx context.Context, runner CmdRunner, fn func(io.Reader) error, name string, args ...string) error {
+	if sr, ok := runner.(StreamCmdRunner); ok {
+		return sr.Stream(ctx, fn, name, args...)
+	}
+	out, err := runner.Run(ctx, name, args...)
+	if ferr := fn(bytes.NewReader(out)); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// waitDelay bounds how long a cancelled command's output is waited for
+// after the command is killed. lsof forks helper processes that can be
+// stuck on a hung network mount and keep its pipes open.
+const waitDelay = time.Second
+
 // RealCmdRunner executes real shell commands.
 type RealCmdRunner struct{}
 
 // Run executes a command and returns its stdout. Stderr is suppressed
-// to prevent it from leaking into TUI output.
+// to prevent it from leaking into TUI output. The command is killed when
+// ctx is done.
 func (r *RealCmdRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Stderr = io.Discard
-	return cmd.Output()
+	cmd.WaitDelay = waitDelay
+	out, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return out, ctx.Err()
+	}
+	return out, err
+}
+
+// Stream executes a command and passes its stdout to fn as it is written.
+// Stderr is left unset, which sends it to the null device without the
+// copying goroutine that Wait would otherwise block on.
+func (r *RealCmdRunner) Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = waitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Killing the command does not end fn's read while a child still
+	// holds the pipe open, so close the pipe too.
+	stop := context.AfterFunc(ctx, func() { stdout.Close() })
+	defer stop()
+
+	if err := fn(stdout); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	// Drain whatever fn left unread so the command is not blocked on a
+	// full pipe.
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
 }
 
 // MockCmdRunner returns canned responses for testing.
@@ -29,6 +106,15 @@ func (m *MockCmdRunner) Run(_ context.Context, _ string, _ ...string) ([]byte, e
 	return m.Output, m.Err
 }
 
+// Stream passes the pre-configured output to fn and returns the
+// pre-configured error.
+func (m *MockCmdRunner) Stream(_ context.Context, fn func(io.Reader) error, _ string, _ ...string) error {
+	if err := fn(bytes.NewReader(m.Output)); err != nil {
+		return err
+	}
+	return m.Err
+}
+
 // MultiMockCmdRunner returns different responses based on the command.
 // Keys are "name arg1 arg2 ..." strings.
 type MultiMockCmdRunner struct {
@@ -53,3 +139,47 @@ func (m *MultiMockCmdRunner) Run(_ context.Context, name string, args ...string)
 	}
 	return nil, nil
 }
+
+// Stream looks up the command key like Run and passes its output to fn.
+func (m *MultiMockCmdRunner) Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error {
+	out, err := m.Run(ctx, name, args...)
+	if ferr := fn(bytes.NewReader(out)); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// RecordingCmdRunner answers like MultiMockCmdRunner and records every
+// command it is asked to run, as "name arg1 arg2 ..." strings.
+type RecordingCmdRunner struct {
+	MultiMockCmdRunner
+	Commands []string
+}
+
+// Run records the command and returns its pre-configured response.
+func (r *RecordingCmdRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	r.Commands = append(r.Commands, strings.Join(append([]string{name}, args...), " "))
+	return r.MultiMockCmdRunner.Run(ctx, name, args...)
+}
+
+// Stream records the command and passes its pre-configured output to fn.
+func (r *RecordingCmdRunner) Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error {
+	r.Commands = append(r.Commands, strings.Join(append([]string{name}, args...), " "))
+	return r.MultiMockCmdRunner.Stream(ctx, fn, name, args...)
+}
+
+// BlockingCmdRunner blocks every command until its context is done, like
+// a command stuck on a hung mount.
+type BlockingCmdRunner struct{}
+
+// Run blocks until ctx is done and returns its error.
+func (r *BlockingCmdRunner) Run(ctx context.Context, _ string, _ ...string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// Stream blocks until ctx is done and returns its error.
+func (r *BlockingCmdRunner) Stream(ctx context.Context, _ func(io.Reader) error, _ string, _ ...string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
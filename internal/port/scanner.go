@@ -2,13 +2,18 @@ package port
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
 	"strings"
 )
 
 // Scanner defines the interface for discovering ports and their processes.
 type Scanner interface {
 	ListPorts(ctx context.Context) ([]PortEntry, error)
+	ListAllPorts(ctx context.Context) ([]PortEntry, error)
 	FindByPort(ctx context.Context, port int) ([]PortEntry, error)
 	FindByProcess(ctx context.Context, name string) ([]PortEntry, error)
 }
@@ -21,38 +26,74 @@ type CmdRunner interface {
 // LsofScanner implements Scanner using macOS lsof.
 type LsofScanner struct {
 	runner CmdRunner
+	users  userCache
+	procs  processCache
 }
 
 // NewLsofScanner creates a new scanner backed by lsof.
 func NewLsofScanner(runner CmdRunner) *LsofScanner {
-	return &LsofScanner{runner: runner}
+	return &LsofScanner{runner: runner, users: userCache{remote: RemoteHost(runner) != ""}}
 }
 
 // ListPorts returns all listening ports.
 func (s *LsofScanner) ListPorts(ctx context.Context) ([]PortEntry, error) {
-	out, err := s.runner.Run(ctx, "lsof", "-iTCP", "-iUDP", "-sTCP:LISTEN", "-P", "-n")
-	if err != nil {
-		return nil, fmt.Errorf("failed to run lsof: %w", err)
-	}
-	return ParseLsofOutput(string(out)), nil
+	return s.run(ctx, "-iTCP", "-iUDP", "-sTCP:LISTEN")
 }
 
 // ListAllPorts returns all connections including ESTABLISHED.
 func (s *LsofScanner) ListAllPorts(ctx context.Context) ([]PortEntry, error) {
-	out, err := s.runner.Run(ctx, "lsof", "-iTCP", "-iUDP", "-P", "-n")
-	if err != nil {
-		return nil, fmt.Errorf("failed to run lsof: %w", err)
-	}
-	return ParseLsofOutput(string(out)), nil
+	return s.run(ctx, "-iTCP", "-iUDP")
 }
 
-// FindByPort returns all entries matching the given port number.
+// FindByPort returns all entries bound to the given port number. lsof's
+// -i:port also matches connections to that port elsewhere; they are
+// dropped while the output is parsed.
 func (s *LsofScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
-	out, err := s.runner.Run(ctx, "lsof", fmt.Sprintf("-i:%d", port), "-P", "-n")
-	if err != nil {
-		return nil, fmt.Errorf("failed to run lsof for port %d: %w", port, err)
+	return s.runFiltered(ctx, func(e PortEntry) bool { return e.Port == port }, fmt.Sprintf("-i:%d", port))
+}
+
+// run queries lsof for the given selectors and keeps every entry.
+func (s *LsofScanner) run(ctx context.Context, selectors ...string) ([]PortEntry, error) {
+	return s.runFiltered(ctx, nil, selectors...)
+}
+
+// runFiltered queries lsof for the given selectors using the -F field
+// format with full command names, keeping the entries accepted by keep as
+// the output is streamed in. If that invocation fails outright, the
+// default column format is tried instead.
+func (s *LsofScanner) runFiltered(ctx context.Context, keep func(PortEntry) bool, selectors ...string) ([]PortEntry, error) {
+	var entries []PortEntry
+	parse := func(r io.Reader) error {
+		var err error
+		entries, err = parseLsofStream(r, keep)
+		return err
 	}
-	return ParseLsofOutput(string(out)), nil
+
+	args := append([]string{"+c", "0", "-F", lsofFieldSpec}, selectors...)
+	err := streamOutput(ctx, s.runner, parse, "lsof", append(args, "-P", "-n")...)
+	if err != nil && !lsofNoMatch(err) && ctx.Err() == nil {
+		err = streamOutput(ctx, s.runner, parse, "lsof", append(selectors, "-P", "-n")...)
+	}
+	if err != nil && !lsofNoMatch(err) {
+		return nil, fmt.Errorf("failed to run lsof: %w", err)
+	}
+
+	// The field format reports UIDs; the column format already has names.
+	for i := range entries {
+		if uid, err := strconv.Atoi(entries[i].User); err == nil {
+			entries[i].User = s.users.name(uid)
+		}
+	}
+	s.procs.enrichFromPs(ctx, s.runner, entries)
+	return entries, nil
+}
+
+// lsofNoMatch reports whether err is lsof's exit status 1, which it uses
+// when some selectors matched nothing or some files could not be read.
+// Whatever it did find is still printed to stdout.
+func lsofNoMatch(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1
 }
 
 // FindByProcess returns all entries matching the given process name.
@@ -61,7 +102,12 @@ func (s *LsofScanner) FindByProcess(ctx context.Context, name string) ([]PortEnt
 	if err != nil {
 		return nil, err
 	}
+	return matchProcess(entries, name), nil
+}
 
+// matchProcess returns the entries whose process name or command contains
+// name, case-insensitively.
+func matchProcess(entries []PortEntry, name string) []PortEntry {
 	var matched []PortEntry
 	lower := strings.ToLower(name)
 	for _, e := range entries {
@@ -70,5 +116,5 @@ func (s *LsofScanner) FindByProcess(ctx context.Context, name string) ([]PortEnt
 			matched = append(matched, e)
 		}
 	}
-	return matched, nil
+	return matched
 }
@@ -0,0 +1,125 @@
+package port
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// builtinServices is used for ports that /etc/services does not list.
+//
+//go:embed services
+var builtinServices string
+
+// servicesPath is the system services database.
+const servicesPath = "/etc/services"
+
+// serviceKey identifies a service by port and transport protocol.
+type serviceKey struct {
+	port  int
+	proto Protocol
+}
+
+// ServiceResolver maps ports to well-known service names and back. Names
+// come from overrides first, then from /etc/services, then from a
+// built-in list that also covers common development ports.
+type ServiceResolver struct {
+	names map[serviceKey]string
+	ports map[string]int // service names and aliases, lower case
+}
+
+// NewServiceResolver loads /etc/services and applies the overrides, which
+// name ports regardless of protocol.
+func NewServiceResolver(overrides map[int]string) *ServiceResolver {
+	return newServiceResolver(servicesPath, overrides)
+}
+
+func newServiceResolver(path string, overrides map[int]string) *ServiceResolver {
+	r := &ServiceResolver{
+		names: make(map[serviceKey]string),
+		ports: make(map[string]int),
+	}
+	for port, name := range overrides {
+		r.add(serviceKey{port, TCP}, name)
+		r.add(serviceKey{port, UDP}, name)
+	}
+	if f, err := os.Open(path); err == nil {
+		r.load(f)
+		f.Close()
+	}
+	r.load(strings.NewReader(builtinServices))
+	return r
+}
+
+// load reads entries in /etc/services format, "name port/protocol
+// [aliases...] [# comment]", keeping names that are already known.
+func (r *ServiceResolver) load(rd io.Reader) {
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		portStr, proto, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil || port <= 0 || port > 65535 {
+			continue
+		}
+		key := serviceKey{port, Protocol(strings.ToUpper(proto))}
+		r.add(key, fields[0], fields[2:]...)
+	}
+}
+
+// add names a port unless it already has a name, and makes the name and
+// its aliases resolve to the port.
+func (r *ServiceResolver) add(key serviceKey, name string, aliases ...string) {
+	if _, ok := r.names[key]; !ok {
+		r.names[key] = name
+	}
+	for _, n := range append([]string{name}, aliases...) {
+		n = strings.ToLower(n)
+		if _, ok := r.ports[n]; !ok {
+			r.ports[n] = key.port
+		}
+	}
+}
+
+// Name returns the service name for a port, or "" if it has none.
+func (r *ServiceResolver) Name(port int, proto Protocol) string {
+	if r == nil {
+		return ""
+	}
+	return r.names[serviceKey{port, proto}]
+}
+
+// Port returns the port of a service name or alias, such as "postgres".
+func (r *ServiceResolver) Port(name string) (int, bool) {
+	if r == nil {
+		return 0, false
+	}
+	port, ok := r.ports[strings.ToLower(name)]
+	return port, ok
+}
+
+// ParsePort parses a port number or a service name.
+func (r *ServiceResolver) ParsePort(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 || n > 65535 {
+			return 0, fmt.Errorf("port %d out of range", n)
+		}
+		return n, nil
+	}
+	if port, ok := r.Port(s); ok {
+		return port, nil
+	}
+	return 0, fmt.Errorf("unknown port or service name %q", s)
+}
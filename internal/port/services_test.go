@@ -0,0 +1,105 @@
+package port
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeServices(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "services")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServiceResolver_Name(t *testing.T) {
+	path := writeServices(t, `# Network services, Internet style
+http		80/tcp		www		# WorldWideWeb HTTP
+postgresql	5432/tcp	postgres	# PostgreSQL Database
+domain		53/udp
+hbci		3000/tcp	# HBCI
+bogus		nope/tcp
+`)
+	r := newServiceResolver(path, map[int]string{8000: "api"})
+
+	tests := []struct {
+		port  int
+		proto Protocol
+		want  string
+	}{
+		{80, TCP, "http"},
+		{5432, TCP, "postgresql"},
+		{53, UDP, "domain"},
+		{3000, TCP, "hbci"},           // the system database wins over the built-in list
+		{5173, TCP, "vite"},           // from the built-in list
+		{9229, TCP, "node-inspector"}, // from the built-in list
+		{8000, TCP, "api"},            // overrides apply to both protocols
+		{8000, UDP, "api"},
+		{80, UDP, ""},
+		{61234, TCP, ""},
+	}
+	for _, tt := range tests {
+		if got := r.Name(tt.port, tt.proto); got != tt.want {
+			t.Errorf("Name(%d, %s): got %q, want %q", tt.port, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestServiceResolver_Overrides(t *testing.T) {
+	path := writeServices(t, "http-alt\t8080/tcp\n")
+	r := newServiceResolver(path, map[int]string{8080: "gateway"})
+	if got := r.Name(8080, TCP); got != "gateway" {
+		t.Errorf("expected the override, got %q", got)
+	}
+	if p, ok := r.Port("gateway"); !ok || p != 8080 {
+		t.Errorf("Port(gateway): got %d, %v", p, ok)
+	}
+}
+
+func TestServiceResolver_MissingFile(t *testing.T) {
+	r := newServiceResolver(filepath.Join(t.TempDir(), "missing"), nil)
+	if got := r.Name(3000, TCP); got != "node" {
+		t.Errorf("expected the built-in name for 3000, got %q", got)
+	}
+	if got := r.Name(22, TCP); got != "ssh" {
+		t.Errorf("expected the built-in name for 22, got %q", got)
+	}
+}
+
+func TestServiceResolver_ParsePort(t *testing.T) {
+	path := writeServices(t, "postgresql\t5432/tcp\tpostgres\n")
+	r := newServiceResolver(path, nil)
+
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"5432", 5432, false},
+		{"postgres", 5432, false},
+		{"PostgreSQL", 5432, false},
+		{"vite", 5173, false},
+		{"0", 0, true},
+		{"70000", 0, true},
+		{"no-such-service", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := r.ParsePort(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePort(%q): got %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestServiceResolver_Nil(t *testing.T) {
+	var r *ServiceResolver
+	if r.Name(80, TCP) != "" {
+		t.Error("expected no name from a nil resolver")
+	}
+	if _, ok := r.Port("http"); ok {
+		t.Error("expected no port from a nil resolver")
+	}
+}
@@ -0,0 +1,156 @@
+package port
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Process describes a process seen during a scan.
+type Process struct {
+	PID        int
+	PPID       int
+	Name       string // short process name
+	Command    string // full command line
+	User       string
+	StartTime  time.Time
+	CPUPercent float64 // average over the process's lifetime on Linux
+	MemRSS     int64   // in bytes
+}
+
+// Snapshot is the result of one collection pass: the sockets and the
+// process table they were joined with, so that callers needing process
+// details do not have to run further commands that may see a different
+// state of the system.
+type Snapshot struct {
+	Entries   []PortEntry
+	Processes map[int]Process
+	Time      time.Time     // when the scan started
+	Duration  time.Duration // how long the scan took
+	Backend   string
+	Host      string // remote host scanned, or "" for this machine
+}
+
+// SnapshotOptions selects which sockets a snapshot collects.
+type SnapshotOptions struct {
+	All  bool   // all connections instead of listeners and UDP sockets
+	Unix bool   // Unix domain sockets instead of TCP and UDP
+	Port int    // only sockets bound to this port, in any state
+	Path string // only Unix sockets bound to this path
+}
+
+// remoteScanner is implemented by scanners that run commands, which may
+// run on another host.
+type remoteScanner interface {
+	host() string
+}
+
+func (s *LsofScanner) host() string    { return RemoteHost(s.runner) }
+func (s *SsScanner) host() string      { return RemoteHost(s.runner) }
+func (s *NetstatScanner) host() string { return RemoteHost(s.runner) }
+
+// TakeSnapshot scans with scanner and returns the sockets together with
+// the process table and container of their owners.
+func TakeSnapshot(ctx context.Context, scanner Scanner, backend string, opts SnapshotOptions) (*Snapshot, error) {
+	start := time.Now()
+
+	// Scanners that build a process table while joining sockets with
+	// their owners store it here.
+	var table map[int]Process
+	ctx = withTableSink(ctx, &table)
+
+	var entries []PortEntry
+	var err error
+	switch {
+	case opts.Path != "":
+		entries, err = FindByPath(ctx, scanner, opts.Path)
+	case opts.Port != 0:
+		entries, err = scanner.FindByPort(ctx, opts.Port)
+		entries = boundTo(entries, opts.Port)
+	case opts.Unix:
+		entries, err = ListUnixSockets(ctx, scanner)
+	case opts.All:
+		entries, err = scanner.ListAllPorts(ctx)
+	default:
+		entries, err = scanner.ListPorts(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var host string
+	if r, ok := scanner.(remoteScanner); ok {
+		host = r.host()
+	}
+	// Containers are found through the local /proc.
+	if host == "" {
+		AttachContainers(entries)
+	}
+
+	return &Snapshot{
+		Entries:   entries,
+		Processes: completeTable(table, entries),
+		Time:      start,
+		Duration:  time.Since(start),
+		Backend:   backend,
+		Host:      host,
+	}, nil
+}
+
+// completeTable returns a copy of table with an entry for every socket
+// owner, filling gaps from the entries themselves.
+func completeTable(table map[int]Process, entries []PortEntry) map[int]Process {
+	procs := make(map[int]Process, len(table))
+	for pid, p := range table {
+		procs[pid] = p
+	}
+	for _, e := range entries {
+		p, ok := procs[e.PID]
+		if !ok {
+			p = Process{
+				PID:       e.PID,
+				PPID:      e.PPID,
+				Name:      e.Process,
+				Command:   e.Command,
+				StartTime: e.StartTime,
+			}
+		}
+		if p.User == "" {
+			p.User = e.User
+		}
+		if p.Name == "" {
+			p.Name = e.Process
+		}
+		procs[e.PID] = p
+	}
+	return procs
+}
+
+// Process returns the process with the given PID.
+func (s *Snapshot) Process(pid int) (Process, bool) {
+	p, ok := s.Processes[pid]
+	return p, ok
+}
+
+// Children returns the PIDs of the direct children of pid, sorted.
+func (s *Snapshot) Children(pid int) []int {
+	var children []int
+	for _, p := range s.Processes {
+		if p.PPID == pid && p.PID != pid {
+			children = append(children, p.PID)
+		}
+	}
+	sort.Ints(children)
+	return children
+}
+
+// boundTo returns the entries whose local port is port. Some backends
+// also match the remote port when searching by port.
+func boundTo(entries []PortEntry, port int) []PortEntry {
+	var bound []PortEntry
+	for _, e := range entries {
+		if e.Port == port {
+			bound = append(bound, e)
+		}
+	}
+	return bound
+}
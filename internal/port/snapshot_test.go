@@ -0,0 +1,88 @@
+package port
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	root := writeEnrichFixture(t)
+	// A worker forked by node that owns no sockets.
+	if err := os.MkdirAll(filepath.Join(root, "301"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFixtureFile(t, root, "301/stat",
+		"301 (node) S 300 300 300 0 -1 4194560 0 0 0 0 1 1 0 0 20 0 1 0 4300 500000 512 0\n")
+
+	snap, err := TakeSnapshot(context.Background(), NewProcScanner(root), "proc", SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.Backend != "proc" || snap.Time.IsZero() {
+		t.Errorf("unexpected metadata: backend %q, time %v", snap.Backend, snap.Time)
+	}
+	if len(snap.Entries) == 0 {
+		t.Fatal("expected entries")
+	}
+
+	node, ok := snap.Process(300)
+	if !ok {
+		t.Fatal("expected node in the process table")
+	}
+	if node.Command != "node server.js --port 3000" || node.User == "" {
+		t.Errorf("node: got %+v", node)
+	}
+	if node.MemRSS != 2000*int64(os.Getpagesize()) {
+		t.Errorf("rss: got %d", node.MemRSS)
+	}
+
+	worker, ok := snap.Process(301)
+	if !ok || worker.Name != "node" || worker.Command != "node" {
+		t.Errorf("worker: got %+v, %v", worker, ok)
+	}
+	if got := snap.Children(300); len(got) != 1 || got[0] != 301 {
+		t.Errorf("children: got %v, want [301]", got)
+	}
+
+	// Owners without a stat file are filled in from their entries.
+	if nginx, ok := snap.Process(100); !ok || nginx.Name != "nginx" {
+		t.Errorf("nginx: got %+v, %v", nginx, ok)
+	}
+
+	snap, err = TakeSnapshot(context.Background(), NewProcScanner(root), "proc", SnapshotOptions{Port: 3000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snap.Entries) != 1 || snap.Entries[0].PID != 300 {
+		t.Errorf("expected only node on port 3000, got %v", snap.Entries)
+	}
+}
+
+func TestTakeSnapshot_Lsof(t *testing.T) {
+	runner := &MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			"lsof +c 0 -F pcuLfatPnT -iTCP -iUDP -P -n": {Output: []byte(
+				"p5678\ncnode\nu501\nf8\nau\ntIPv6\nPTCP\nn*:3000\nTST=LISTEN\n")},
+			"ps -A -ww -o " + psTableFormat: {Output: []byte(
+				"  812     1 alice  0.0  2048 Thu Feb 13 10:29:58 2026 -zsh\n" +
+					" 5678   812 alice  1.5 81920 Thu Feb 13 10:30:00 2026 node server.js\n")},
+		},
+	}
+
+	snap, err := TakeSnapshot(context.Background(), NewLsofScanner(runner), "lsof", SnapshotOptions{All: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snap.Entries) != 1 || snap.Entries[0].PPID != 812 {
+		t.Fatalf("unexpected entries: %+v", snap.Entries)
+	}
+	shell, ok := snap.Process(812)
+	if !ok || shell.Command != "-zsh" {
+		t.Errorf("shell: got %+v, %v", shell, ok)
+	}
+	if node, _ := snap.Process(5678); node.Name != "node" || node.CPUPercent != 1.5 {
+		t.Errorf("node: got %+v", node)
+	}
+}
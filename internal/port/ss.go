@@ -0,0 +1,203 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SsScanner implements Scanner using ss from iproute2.
+type SsScanner struct {
+	runner CmdRunner
+	users  userCache
+	procs  processCache
+}
+
+// NewSsScanner creates a new scanner backed by ss.
+func NewSsScanner(runner CmdRunner) *SsScanner {
+	return &SsScanner{runner: runner, users: userCache{remote: RemoteHost(runner) != ""}}
+}
+
+// ListPorts returns all listening ports.
+func (s *SsScanner) ListPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.run(ctx, "-tulpnHe")
+}
+
+// ListAllPorts returns all connections including ESTABLISHED.
+func (s *SsScanner) ListAllPorts(ctx context.Context) ([]PortEntry, error) {
+	return s.run(ctx, "-tuapnHe")
+}
+
+// FindByPort returns all entries matching the given port number.
+func (s *SsScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
+	return s.run(ctx, "-tuapnHe", "sport", "=", fmt.Sprintf(":%d", port))
+}
+
+// FindByProcess returns all entries matching the given process name.
+func (s *SsScanner) FindByProcess(ctx context.Context, name string) ([]PortEntry, error) {
+	entries, err := s.ListPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchProcess(entries, name), nil
+}
+
+func (s *SsScanner) run(ctx context.Context, args ...string) ([]PortEntry, error) {
+	out, err := s.runner.Run(ctx, "ss", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ss: %w", err)
+	}
+
+	entries := ParseSsOutput(string(out))
+	for i := range entries {
+		if uid, err := strconv.Atoi(entries[i].User); err == nil {
+			entries[i].User = s.users.name(uid)
+		}
+	}
+	s.procs.enrichFromPs(ctx, s.runner, entries)
+	return entries, nil
+}
+
+// ParseSsOutput parses the output from ss -tulpnHe.
+// Each line has fields:
+// Netid State Recv-Q Send-Q Local:Port Peer:Port users:((...)) [uid:N] ino:N ...
+// A socket held by several processes yields one entry per owner. Sockets
+// without a visible owner are skipped, the same as lsof. The User field
+// holds the numeric UID; ss omits it for root.
+func ParseSsOutput(output string) []PortEntry {
+	var entries []PortEntry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		entries = append(entries, parseSsLine(line)...)
+	}
+	return entries
+}
+
+// parseSsLine parses a single ss output line into one entry per owner.
+func parseSsLine(line string) []PortEntry {
+	fields := strings.Fields(line)
+	if len(fields) < 7 {
+		return nil
+	}
+
+	var proto Protocol
+	switch fields[0] {
+	case "tcp":
+		proto = TCP
+	case "udp":
+		proto = UDP
+	default:
+		return nil
+	}
+
+	state, ok := ParseState(fields[1])
+	if !ok {
+		return nil
+	}
+
+	local, ok := parseEndpoint(fields[4])
+	if !ok || local.port < 0 {
+		return nil
+	}
+	// ss prints a dual-stack wildcard as "*" and a v6-only one as "[::]".
+	addr, family := bindAddr(local.addr, "", v6OnlyUnknown)
+
+	var remote endpoint
+	if ep, ok := parseEndpoint(fields[5]); ok {
+		remote = ep
+	}
+	remoteAddr, remotePort := peerAddr(remote.addr, remote.port)
+
+	if proto == UDP {
+		state = udpState(remotePort)
+	}
+
+	uid := "0"
+	if idx := strings.Index(line, " uid:"); idx != -1 {
+		rest := line[idx+len(" uid:"):]
+		if end := strings.IndexByte(rest, ' '); end != -1 {
+			rest = rest[:end]
+		}
+		uid = rest
+	}
+
+	var entries []PortEntry
+	for _, u := range parseSsUsers(line) {
+		entries = append(entries, PortEntry{
+			Process:    u.name,
+			PID:        u.pid,
+			User:       uid,
+			FD:         u.fd,
+			Protocol:   proto,
+			Port:       local.port,
+			State:      state,
+			Command:    u.name,
+			LocalAddr:  addr,
+			Family:     family,
+			Zone:       local.zone,
+			RemoteAddr: remoteAddr,
+			RemotePort: remotePort,
+		})
+	}
+	return entries
+}
+
+// ssUser is one owner from an ss users:(...) column.
+type ssUser struct {
+	name string
+	pid  int
+	fd   string
+}
+
+// parseSsUsers extracts owners from a column like
+// users:(("node",pid=123,fd=20),("node",pid=124,fd=20)).
+// Process names are quoted and may contain spaces or commas.
+func parseSsUsers(line string) []ssUser {
+	idx := strings.Index(line, "users:(")
+	if idx == -1 {
+		return nil
+	}
+	rest := line[idx+len("users:("):]
+
+	var users []ssUser
+	for {
+		start := strings.Index(rest, `("`)
+		if start == -1 {
+			break
+		}
+		rest = rest[start+2:]
+
+		end := strings.Index(rest, `",`)
+		if end == -1 {
+			break
+		}
+		name := rest[:end]
+		rest = rest[end+2:]
+
+		closeParen := strings.IndexByte(rest, ')')
+		if closeParen == -1 {
+			break
+		}
+		attrs := rest[:closeParen]
+		rest = rest[closeParen+1:]
+
+		u := ssUser{name: name}
+		for _, attr := range strings.Split(attrs, ",") {
+			key, val, _ := strings.Cut(attr, "=")
+			switch key {
+			case "pid":
+				u.pid, _ = strconv.Atoi(val)
+			case "fd":
+				u.fd = val + "u"
+			}
+		}
+		if u.pid > 0 {
+			users = append(users, u)
+		}
+	}
+	return users
+}
@@ -0,0 +1,183 @@
+package port
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseSsOutput(t *testing.T) {
+	input := `tcp LISTEN 0      511          0.0.0.0:80        0.0.0.0:*     users:(("nginx",pid=1234,fd=6),("nginx",pid=1235,fd=6)) ino:1001 sk:1 cgroup:/ <->
+tcp LISTEN 0      511             [::]:443          [::]:*     users:(("nginx",pid=1234,fd=7)) v6only:1 ino:1002 sk:2 cgroup:/ <->
+tcp LISTEN 0      4096               *:3000            *:*     users:(("node",pid=5678,fd=20)) uid:1000 ino:1003 sk:3 cgroup:/user.slice <->
+tcp LISTEN 0      244          [::1]:5432          [::]:*     users:(("postgres",pid=9012,fd=9)) uid:999 ino:1004 sk:4 cgroup:/ <->
+udp UNCONN 0      0      127.0.0.53%lo:53        0.0.0.0:*     users:(("systemd-resolve",pid=400,fd=13)) uid:101 ino:1005 sk:5 cgroup:/ <->
+tcp LISTEN 0      128          0.0.0.0:2024      0.0.0.0:*     ino:662 sk:6 cgroup:/ <->
+`
+
+	entries := ParseSsOutput(input)
+
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx     int
+		process string
+		pid     int
+		user    string
+		fd      string
+		port    int
+		proto   Protocol
+		state   State
+	}{
+		{0, "nginx", 1234, "0", "6u", 80, TCP, "LISTEN"},
+		{1, "nginx", 1235, "0", "6u", 80, TCP, "LISTEN"},
+		{2, "nginx", 1234, "0", "7u", 443, TCP, "LISTEN"},
+		{3, "node", 5678, "1000", "20u", 3000, TCP, "LISTEN"},
+		{4, "postgres", 9012, "999", "9u", 5432, TCP, "LISTEN"},
+		{5, "systemd-resolve", 400, "101", "13u", 53, UDP, "LISTEN"},
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.Process != tt.process {
+			t.Errorf("[%d] process: got %q, want %q", tt.idx, e.Process, tt.process)
+		}
+		if e.PID != tt.pid {
+			t.Errorf("[%d] pid: got %d, want %d", tt.idx, e.PID, tt.pid)
+		}
+		if e.User != tt.user {
+			t.Errorf("[%d] user: got %q, want %q", tt.idx, e.User, tt.user)
+		}
+		if e.FD != tt.fd {
+			t.Errorf("[%d] fd: got %q, want %q", tt.idx, e.FD, tt.fd)
+		}
+		if e.Port != tt.port {
+			t.Errorf("[%d] port: got %d, want %d", tt.idx, e.Port, tt.port)
+		}
+		if e.Protocol != tt.proto {
+			t.Errorf("[%d] protocol: got %q, want %q", tt.idx, e.Protocol, tt.proto)
+		}
+		if e.State != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, e.State, tt.state)
+		}
+	}
+
+	wantBinds := []struct {
+		addr   string
+		family Family
+		zone   string
+	}{
+		{"*", IPv4, ""},
+		{"*", IPv4, ""},
+		{"*", IPv6, ""},
+		{"*", DualStack, ""},
+		{"::1", IPv6, ""},
+		{"127.0.0.53", IPv4, "lo"},
+	}
+	for i, w := range wantBinds {
+		e := entries[i]
+		if e.LocalAddr != w.addr || e.Family != w.family || e.Zone != w.zone {
+			t.Errorf("[%d] bind: got %s%%%s (%s), want %s%%%s (%s)",
+				i, e.LocalAddr, e.Zone, e.Family, w.addr, w.zone, w.family)
+		}
+	}
+}
+
+func TestParseSsOutput_Established(t *testing.T) {
+	input := `tcp ESTAB      0      0      192.168.1.10:54321 93.184.216.34:443 users:(("chrome",pid=1111,fd=20)) timer:(keepalive,28sec,0) uid:1000 ino:3670 sk:7 <->
+tcp CLOSE-WAIT 1      0      [::1]:5432         [::1]:50000       users:(("postgres",pid=9012,fd=11)) uid:999 ino:3671 sk:8 <->
+udp ESTAB      0      0      10.0.0.2:41000     10.0.0.1:53       users:(("resolver",pid=500,fd=3)) ino:3672 sk:9 <->
+`
+
+	entries := ParseSsOutput(input)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	wantStates := []State{"ESTABLISHED", "CLOSE_WAIT", "ESTABLISHED"}
+	wantPorts := []int{54321, 5432, 41000}
+	wantRemotes := []string{"93.184.216.34:443", "[::1]:50000", "10.0.0.1:53"}
+	for i, e := range entries {
+		if e.Remote() != wantRemotes[i] {
+			t.Errorf("[%d] remote: got %q, want %q", i, e.Remote(), wantRemotes[i])
+		}
+		if e.State != wantStates[i] {
+			t.Errorf("[%d] state: got %q, want %q", i, e.State, wantStates[i])
+		}
+		if e.Port != wantPorts[i] {
+			t.Errorf("[%d] port: got %d, want %d", i, e.Port, wantPorts[i])
+		}
+	}
+}
+
+func TestParseSsOutput_ProcessNameWithSpaces(t *testing.T) {
+	input := `tcp LISTEN 0 128 127.0.0.1:9222 0.0.0.0:* users:(("Web Content, Inc",pid=77,fd=31)) uid:1000 ino:5 sk:1 <->
+`
+
+	entries := ParseSsOutput(input)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Process != "Web Content, Inc" {
+		t.Errorf("process: got %q, want %q", entries[0].Process, "Web Content, Inc")
+	}
+	if entries[0].PID != 77 {
+		t.Errorf("pid: got %d, want 77", entries[0].PID)
+	}
+}
+
+func TestParseSsOutput_EmptyInput(t *testing.T) {
+	if entries := ParseSsOutput(""); len(entries) != 0 {
+		t.Errorf("expected 0 entries, got %d", len(entries))
+	}
+}
+
+func TestParseSsUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []ssUser
+	}{
+		{"single", `users:(("node",pid=123,fd=20))`, []ssUser{{"node", 123, "20u"}}},
+		{"multiple", `users:(("a",pid=1,fd=3),("b",pid=2,fd=4))`, []ssUser{{"a", 1, "3u"}, {"b", 2, "4u"}}},
+		{"none", `ino:662 sk:6`, nil},
+		{"malformed", `users:(("node",pid=x`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSsUsers(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d users, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("[%d]: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSsScanner_FindByPort(t *testing.T) {
+	runner := &MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			"ss -tuapnHe sport = :5432": {Output: []byte(
+				`tcp LISTEN 0 244 127.0.0.1:5432 0.0.0.0:* users:(("postgres",pid=9012,fd=9)) ino:1004 sk:4 <->` + "\n",
+			)},
+		},
+	}
+	s := NewSsScanner(runner)
+
+	entries, err := s.FindByPort(context.Background(), 5432)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].User != "root" {
+		t.Errorf("user: got %q, want root", entries[0].User)
+	}
+}
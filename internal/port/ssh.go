@@ -0,0 +1,88 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// sshConnectFailed is the exit status ssh uses for its own errors, as
+// opposed to the exit status of the remote command.
+const sshConnectFailed = 255
+
+// SSHCmdRunner runs commands on a remote host through ssh, so that the
+// command-based backends and the process manager can inspect another
+// machine. ssh runs non-interactively and must be able to log in without
+// a password prompt, for example with an agent or a key.
+type SSHCmdRunner struct {
+	host   string
+	runner CmdRunner
+}
+
+// NewSSHCmdRunner creates a runner for host, given as [user@]hostname or
+// an alias from ~/.ssh/config. ssh itself is run with runner.
+func NewSSHCmdRunner(host string, runner CmdRunner) *SSHCmdRunner {
+	return &SSHCmdRunner{host: host, runner: runner}
+}
+
+// Host returns the remote host commands run on.
+func (r *SSHCmdRunner) Host() string {
+	return r.host
+}
+
+// Run executes a command on the remote host and returns its stdout.
+func (r *SSHCmdRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := r.runner.Run(ctx, "ssh", r.sshArgs(name, args)...)
+	return out, r.wrapErr(err)
+}
+
+// Stream executes a command on the remote host and passes its stdout to
+// fn as it arrives.
+func (r *SSHCmdRunner) Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error {
+	return r.wrapErr(streamOutput(ctx, r.runner, fn, "ssh", r.sshArgs(name, args)...))
+}
+
+// sshArgs builds the ssh arguments that run a command remotely. ssh
+// passes the command to the remote user's shell as a single string, so
+// every argument is quoted.
+func (r *SSHCmdRunner) sshArgs(name string, args []string) []string {
+	words := make([]string, 0, len(args)+1)
+	words = append(words, shellQuote(name))
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	return []string{"-o", "BatchMode=yes", "-T", "--", r.host, strings.Join(words, " ")}
+}
+
+// wrapErr describes ssh's own failures, such as an unreachable host or a
+// rejected key. Errors from the remote command are returned as they are,
+// so that exit statuses such as lsof's 1 keep their meaning.
+func (r *SSHCmdRunner) wrapErr(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == sshConnectFailed {
+		return fmt.Errorf("failed to connect to %s over ssh: %w", r.host, err)
+	}
+	return err
+}
+
+// RemoteHost returns the host that runner runs commands on, or "" if it
+// runs them locally.
+func RemoteHost(runner CmdRunner) string {
+	if r, ok := runner.(*SSHCmdRunner); ok {
+		return r.host
+	}
+	return ""
+}
+
+// shellQuote quotes s for a POSIX shell. Words made only of characters
+// that are never special are left as they are, which keeps the commands
+// readable in logs and tests.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,174 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const sshPrefix = "ssh -o BatchMode=yes -T -- dev@box "
+
+func TestSSHCmdRunner_WrapsCommands(t *testing.T) {
+	rec := &RecordingCmdRunner{}
+	r := NewSSHCmdRunner("dev@box", rec)
+
+	r.Run(context.Background(), "ps", "-A", "-ww", "-o", psTableFormat)
+	r.Run(context.Background(), "ls", "/tmp/my dir", "it's")
+	r.Stream(context.Background(), func(r io.Reader) error { return nil }, "lsof", "-iTCP", "-sTCP:LISTEN")
+
+	want := []string{
+		sshPrefix + "ps -A -ww -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=",
+		sshPrefix + `ls '/tmp/my dir' 'it'\''s'`,
+		sshPrefix + "lsof -iTCP -sTCP:LISTEN",
+	}
+	if strings.Join(rec.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\ngot  %q\nwant %q", rec.Commands, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"lsof", "lsof"},
+		{"-sTCP:LISTEN", "-sTCP:LISTEN"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"$(reboot)", "'$(reboot)'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// The quoting must survive a real shell.
+func TestShellQuote_Shell(t *testing.T) {
+	args := []string{"plain", "two words", "it's", `"$HOME"`, "`id`", "a\nb"}
+	var words []string
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	out, err := exec.Command("sh", "-c", `for a in `+strings.Join(words, " ")+`; do printf '%s\0' "$a"; done`).Output()
+	if err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if strings.Join(got, "|") != strings.Join(args, "|") {
+		t.Errorf("got %q, want %q", got, args)
+	}
+}
+
+func TestSSHCmdRunner_ConnectError(t *testing.T) {
+	err := exec.Command("sh", "-c", "exit 255").Run()
+	r := NewSSHCmdRunner("dev@box", &MockCmdRunner{Err: err})
+
+	_, err = r.Run(context.Background(), "lsof")
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to dev@box") {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+
+	// Exit statuses of the remote command are passed through.
+	exit1 := exec.Command("sh", "-c", "exit 1").Run()
+	r = NewSSHCmdRunner("dev@box", &MockCmdRunner{Err: exit1})
+	if _, err := r.Run(context.Background(), "lsof"); !errors.Is(err, exit1) {
+		t.Errorf("expected the remote exit status, got %v", err)
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	if h := RemoteHost(NewSSHCmdRunner("dev@box", &MockCmdRunner{})); h != "dev@box" {
+		t.Errorf("got %q, want dev@box", h)
+	}
+	if h := RemoteHost(&RealCmdRunner{}); h != "" {
+		t.Errorf("got %q for a local runner", h)
+	}
+}
+
+func probeKey(commands ...string) string {
+	script := "uname -s"
+	for _, c := range commands {
+		script += "; command -v " + c
+	}
+	return sshPrefix + "sh -c '" + script + "; true'"
+}
+
+func TestNewRemoteScanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		probe   string
+		probed  []string
+		want    string
+		wantErr string
+	}{
+		{"linux prefers ss", "", "Linux\n/usr/bin/ss\n/usr/bin/lsof\n", []string{"ss", "lsof", "netstat"}, "ss", ""},
+		{"macOS uses lsof", "auto", "Darwin\n/usr/sbin/lsof\n/usr/sbin/netstat\n", []string{"ss", "lsof", "netstat"}, "lsof", ""},
+		{"explicit", "netstat", "Linux\n/bin/netstat\n", []string{"netstat"}, "netstat", ""},
+		{"missing", "lsof", "Linux\n", []string{"lsof"}, "", `backend "lsof" is not available on dev@box`},
+		{"nothing", "", "Linux\n", []string{"ss", "lsof", "netstat"}, "", "no port scanner backend available on dev@box"},
+		{"native", "netlink", "", nil, "", "cannot scan a remote host"},
+		{"unknown", "bogus", "", nil, "", "unknown backend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &RecordingCmdRunner{MultiMockCmdRunner: MultiMockCmdRunner{Responses: map[string]MockResponse{
+				probeKey(tt.probed...): {Output: []byte(tt.probe)},
+			}}}
+			_, got, err := NewRemoteScanner(context.Background(), tt.backend, NewSSHCmdRunner("dev@box", rec))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("backend: got %q, want %q", got, tt.want)
+			}
+			if len(rec.Commands) != 1 || rec.Commands[0] != probeKey(tt.probed...) {
+				t.Errorf("expected one probe, got %q", rec.Commands)
+			}
+		})
+	}
+}
+
+// A remote scan runs everything through ssh, takes user names from the
+// remote process table and skips the local container lookup.
+func TestTakeSnapshot_Remote(t *testing.T) {
+	rec := &RecordingCmdRunner{MultiMockCmdRunner: MultiMockCmdRunner{Responses: map[string]MockResponse{
+		sshPrefix + "ss -tulpnHe": {Output: []byte(
+			"tcp LISTEN 0 4096 *:3000 *:* users:((\"node\",pid=5678,fd=20)) uid:1000 ino:1003 sk:3 cgroup:/user.slice <->\n")},
+		sshPrefix + "ps -A -ww -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=": {Output: []byte(
+			" 5678   812 alice  1.5 81920 Thu Feb 13 10:30:00 2026 node server.js\n")},
+	}}}
+	s := NewSsScanner(NewSSHCmdRunner("dev@box", rec))
+
+	snap, err := TakeSnapshot(context.Background(), s, "ss", SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if snap.Host != "dev@box" {
+		t.Errorf("host: got %q, want dev@box", snap.Host)
+	}
+	if len(snap.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(snap.Entries))
+	}
+	e := snap.Entries[0]
+	if e.User != "alice" {
+		t.Errorf("user: got %q, want the remote name alice", e.User)
+	}
+	if e.Command != "node server.js" {
+		t.Errorf("command: got %q", e.Command)
+	}
+	for _, c := range rec.Commands {
+		if !strings.HasPrefix(c, sshPrefix) {
+			t.Errorf("command not run over ssh: %q", c)
+		}
+	}
+}
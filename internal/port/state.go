@@ -0,0 +1,157 @@
+package port
+
+import (
+	"fmt"
+	"strings"
+)
+
+// State is the state of a socket. TCP sockets use the states from RFC 793
+// with the spelling lsof uses on Linux. UDP sockets have no real state and
+// are reported as LISTEN when unconnected and ESTABLISHED when connected.
+type State string
+
+const (
+	StateListen      State = "LISTEN"
+	StateSynSent     State = "SYN_SENT"
+	StateSynRecv     State = "SYN_RECV"
+	StateEstablished State = "ESTABLISHED"
+	StateFinWait1    State = "FIN_WAIT1"
+	StateFinWait2    State = "FIN_WAIT2"
+	StateCloseWait   State = "CLOSE_WAIT"
+	StateClosing     State = "CLOSING"
+	StateLastAck     State = "LAST_ACK"
+	StateTimeWait    State = "TIME_WAIT"
+	StateClose       State = "CLOSE"
+	StateUnknown     State = "UNKNOWN"
+)
+
+// States lists every known state in the order of the TCP state machine.
+var States = []State{
+	StateListen,
+	StateSynSent,
+	StateSynRecv,
+	StateEstablished,
+	StateFinWait1,
+	StateFinWait2,
+	StateCloseWait,
+	StateClosing,
+	StateLastAck,
+	StateTimeWait,
+	StateClose,
+}
+
+// stateAliases maps the spellings used by lsof, ss, netstat and the
+// kernel, after normalizeStateName, to a State.
+var stateAliases = map[string]State{
+	"LISTEN":      StateListen,
+	"LISTENING":   StateListen,
+	"SYNSENT":     StateSynSent,
+	"SYNRECV":     StateSynRecv,
+	"SYNRECEIVED": StateSynRecv,
+	"ESTABLISHED": StateEstablished,
+	"ESTAB":       StateEstablished,
+	"CONNECTED":   StateEstablished,
+	"FINWAIT1":    StateFinWait1,
+	"FINWAIT2":    StateFinWait2,
+	"CLOSEWAIT":   StateCloseWait,
+	"CLOSING":     StateClosing,
+	"LASTACK":     StateLastAck,
+	"TIMEWAIT":    StateTimeWait,
+	"CLOSE":       StateClose,
+	"CLOSED":      StateClose,
+	"UNCONN":      StateClose,
+	"UNCONNECTED": StateClose,
+}
+
+// normalizeStateName upper-cases a state and drops separators so that
+// "CLOSE_WAIT", "CLOSE-WAIT" and "close wait" compare equal.
+func normalizeStateName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(s)))
+}
+
+// ParseState converts a state name as printed by any backend, e.g.
+// "ESTAB", "CLOSE-WAIT" or "FIN_WAIT_2", into a State. Unrecognized names
+// return StateUnknown and false.
+func ParseState(s string) (State, bool) {
+	st, ok := stateAliases[normalizeStateName(s)]
+	if !ok {
+		return StateUnknown, false
+	}
+	return st, true
+}
+
+// udpState returns the state reported for a UDP socket, which depends only
+// on whether it is connected to a peer.
+func udpState(remotePort int) State {
+	if remotePort > 0 {
+		return StateEstablished
+	}
+	return StateListen
+}
+
+// StateFilter is a set of states to match against.
+type StateFilter map[State]bool
+
+// ParseStateFilter parses one or more state names; each value may itself be
+// a comma-separated list. "fin_wait" selects both FIN_WAIT1 and FIN_WAIT2.
+func ParseStateFilter(values []string) (StateFilter, error) {
+	f := make(StateFilter)
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			if normalizeStateName(name) == "FINWAIT" {
+				f[StateFinWait1] = true
+				f[StateFinWait2] = true
+				continue
+			}
+			st, ok := ParseState(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown state %q (valid: %s)", name, stateList())
+			}
+			f[st] = true
+		}
+	}
+	if len(f) == 0 {
+		return nil, fmt.Errorf("no states given")
+	}
+	return f, nil
+}
+
+// Match reports whether the state is in the filter.
+func (f StateFilter) Match(s State) bool {
+	return f[s]
+}
+
+// OnlyListen reports whether the filter selects nothing but LISTEN, in
+// which case a listening-only scan is enough.
+func (f StateFilter) OnlyListen() bool {
+	return len(f) == 1 && f[StateListen]
+}
+
+// String returns the states in the filter in state machine order.
+func (f StateFilter) String() string {
+	var names []string
+	for _, st := range States {
+		if f[st] {
+			names = append(names, string(st))
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// stateList returns the valid state names for error messages.
+func stateList() string {
+	names := make([]string, len(States))
+	for i, st := range States {
+		names[i] = string(st)
+	}
+	return strings.Join(names, ", ")
+}
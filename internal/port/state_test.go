@@ -0,0 +1,83 @@
+package port
+
+import "testing"
+
+func TestParseState(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   State
+		wantOK bool
+	}{
+		{"LISTEN", StateListen, true},
+		{"ESTAB", StateEstablished, true},
+		{"ESTABLISHED", StateEstablished, true},
+		{"SYN-SENT", StateSynSent, true},
+		{"SYN_RECV", StateSynRecv, true},
+		{"FIN-WAIT-1", StateFinWait1, true},
+		{"FIN_WAIT_2", StateFinWait2, true},
+		{"FIN_WAIT2", StateFinWait2, true},
+		{"CLOSE-WAIT", StateCloseWait, true},
+		{"close_wait", StateCloseWait, true},
+		{"TIME-WAIT", StateTimeWait, true},
+		{"LAST_ACK", StateLastAck, true},
+		{"CLOSING", StateClosing, true},
+		{"CLOSED", StateClose, true},
+		{"UNCONN", StateClose, true},
+		{"IDLE", StateUnknown, false},
+		{"", StateUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseState(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseStateFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{"single", []string{"close_wait"}, "CLOSE_WAIT", false},
+		{"comma separated", []string{"listen,established"}, "LISTEN,ESTABLISHED", false},
+		{"repeated", []string{"time-wait", "LISTEN"}, "LISTEN,TIME_WAIT", false},
+		{"fin wait expands", []string{"fin_wait"}, "FIN_WAIT1,FIN_WAIT2", false},
+		{"unknown", []string{"listen,bogus"}, "", true},
+		{"empty", []string{""}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseStateFilter(tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := f.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateFilter_OnlyListen(t *testing.T) {
+	f, _ := ParseStateFilter([]string{"listen"})
+	if !f.OnlyListen() {
+		t.Error("expected LISTEN-only filter")
+	}
+	f, _ = ParseStateFilter([]string{"listen,close_wait"})
+	if f.OnlyListen() {
+		t.Error("expected filter with CLOSE_WAIT not to be LISTEN-only")
+	}
+}
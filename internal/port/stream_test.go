@@ -0,0 +1,172 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// lsofFixtureLines is the size of the synthetic benchmark fixtures.
+const lsofFixtureLines = 100_000
+
+// syntheticLsofFields returns lsof -F output of at least lines lines for a
+// busy host: every process has one listener and many connections.
+func syntheticLsofFields(lines int) []byte {
+	var b bytes.Buffer
+	for pid := 1000; b.Len() == 0 || bytes.Count(b.Bytes(), []byte{'\n'}) < lines; pid++ {
+		fmt.Fprintf(&b, "p%d\ncworker-%d\nu1000\nLci\n", pid, pid%50)
+		fmt.Fprintf(&b, "f3\nau\ntIPv4\nPTCP\nn*:%d\nTST=LISTEN\nTQR=0\nTQS=0\n", 20000+pid%40000)
+		for fd := 4; fd < 28; fd++ {
+			fmt.Fprintf(&b, "f%d\nau\ntIPv4\nPTCP\nn10.0.%d.%d:%d->10.1.0.%d:5432\nTST=ESTABLISHED\nTQR=0\nTQS=0\n",
+				fd, pid%250, fd, 30000+fd, pid%250)
+		}
+	}
+	return b.Bytes()
+}
+
+// syntheticLsofColumns returns the same kind of host as column output.
+func syntheticLsofColumns(lines int) []byte {
+	var b bytes.Buffer
+	b.WriteString("COMMAND     PID      USER   FD   TYPE             DEVICE SIZE/OFF NODE NAME\n")
+	for i := 0; i < lines; i++ {
+		pid := 1000 + i/25
+		if i%25 == 0 {
+			fmt.Fprintf(&b, "worker   %d   ci    3u  IPv4 0x%x      0t0  TCP *:%d (LISTEN)\n", pid, i, 20000+pid%40000)
+			continue
+		}
+		fmt.Fprintf(&b, "worker   %d   ci   %du  IPv4 0x%x      0t0  TCP 10.0.0.%d:%d->10.1.0.5:5432 (ESTABLISHED)\n",
+			pid, i%25+3, i, pid%250, 30000+i%25)
+	}
+	return b.Bytes()
+}
+
+func listening(e PortEntry) bool { return e.State == StateListen }
+
+func TestParseLsofFieldReader_Keep(t *testing.T) {
+	data := syntheticLsofFields(1000)
+
+	all, err := ParseLsofFieldReader(bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := ParseLsofFieldOutput(string(data)); len(all) != len(want) {
+		t.Fatalf("reader found %d entries, string parser %d", len(all), len(want))
+	}
+
+	listeners, err := ParseLsofFieldReader(bytes.NewReader(data), listening)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(listeners) == 0 || len(listeners)*25 != len(all) {
+		t.Fatalf("expected one listener per 25 entries, got %d of %d", len(listeners), len(all))
+	}
+	if e := listeners[0]; e.PID != 1000 || e.Port != 21000 || e.FD != "3u" || e.User != "ci" {
+		t.Errorf("unexpected first listener: %+v", e)
+	}
+}
+
+func TestParseLsofReader_Keep(t *testing.T) {
+	data := syntheticLsofColumns(100)
+
+	entries, err := ParseLsofReader(bytes.NewReader(data), listening)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 listeners, got %d", len(entries))
+	}
+	if len(ParseLsofOutput(string(data))) != 100 {
+		t.Error("expected the string parser to return every entry")
+	}
+}
+
+func TestParseLsofFieldReader_LongLine(t *testing.T) {
+	input := "p1\ncx\nu0\nf3\ntunix\nn/" + strings.Repeat("a", maxLsofLine) + "\n"
+	if _, err := parseLsofFields(strings.NewReader(input), (*lsofFile).unixEntry, nil); err == nil {
+		t.Error("expected an error for a line over the limit")
+	}
+}
+
+func TestRealCmdRunner_Stream(t *testing.T) {
+	runner := &RealCmdRunner{}
+	ctx := context.Background()
+
+	var got string
+	err := runner.Stream(ctx, func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		got = string(data)
+		return err
+	}, "sh", "-c", "printf 'p1\\ncsh\\n'; exit 1")
+	if got != "p1\ncsh\n" {
+		t.Errorf("output: got %q", got)
+	}
+	if !lsofNoMatch(err) {
+		t.Errorf("expected exit status 1, got %v", err)
+	}
+
+	// A parse error stops the command.
+	stop := fmt.Errorf("stop")
+	err = runner.Stream(ctx, func(io.Reader) error { return stop }, "yes")
+	if err != stop {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+}
+
+// readBuffered reads a command's whole output the way CmdRunner.Run does
+// before it can be parsed.
+func readBuffered(b *testing.B, data []byte) string {
+	out, err := io.ReadAll(bytes.NewReader(data))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(out)
+}
+
+func BenchmarkParseLsofField(b *testing.B) {
+	data := syntheticLsofFields(lsofFixtureLines)
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			ParseLsofFieldOutput(readBuffered(b, data))
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := ParseLsofFieldReader(bytes.NewReader(data), nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("stream-listeners", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := ParseLsofFieldReader(bytes.NewReader(data), listening); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParseLsofColumns(b *testing.B) {
+	data := syntheticLsofColumns(lsofFixtureLines)
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			ParseLsofOutput(readBuffered(b, data))
+		}
+	})
+	b.Run("stream-listeners", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := ParseLsofReader(bytes.NewReader(data), listening); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// expectPrompt fails the test if fn takes much longer than the timeout
+// it was given.
+func expectPrompt(t *testing.T, timeout time.Duration, fn func()) {
+	t.Helper()
+	start := time.Now()
+	fn()
+	if elapsed := time.Since(start); elapsed > timeout+2*time.Second {
+		t.Errorf("took %v with a %v timeout", elapsed, timeout)
+	}
+}
+
+func TestScanners_Timeout(t *testing.T) {
+	runner := &BlockingCmdRunner{}
+	scanners := map[string]Scanner{
+		"lsof":    NewLsofScanner(runner),
+		"ss":      NewSsScanner(runner),
+		"netstat": NewNetstatScanner(runner),
+	}
+	for name, s := range scanners {
+		t.Run(name, func(t *testing.T) {
+			calls := map[string]func(context.Context) error{
+				"ListPorts": func(ctx context.Context) error {
+					_, err := s.ListPorts(ctx)
+					return err
+				},
+				"ListAllPorts": func(ctx context.Context) error {
+					_, err := s.ListAllPorts(ctx)
+					return err
+				},
+				"FindByPort": func(ctx context.Context) error {
+					_, err := s.FindByPort(ctx, 3000)
+					return err
+				},
+			}
+			for call, fn := range calls {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				var err error
+				expectPrompt(t, 20*time.Millisecond, func() { err = fn(ctx) })
+				cancel()
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("%s: expected a deadline error, got %v", call, err)
+				}
+			}
+		})
+	}
+}
+
+func TestTakeSnapshot_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := TakeSnapshot(ctx, NewLsofScanner(&BlockingCmdRunner{}), "lsof", SnapshotOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestProcScanner_Cancelled(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.ListPorts(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+}
+
+// A command whose child keeps stdout open must still return promptly when
+// its context is done.
+func TestRealCmdRunner_Timeout(t *testing.T) {
+	r := &RealCmdRunner{}
+	const timeout = 100 * time.Millisecond
+
+	t.Run("Run", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		var err error
+		expectPrompt(t, timeout, func() { _, err = r.Run(ctx, "sh", "-c", "sleep 5 & sleep 5") })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected a deadline error, got %v", err)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		var err error
+		expectPrompt(t, timeout, func() {
+			err = r.Stream(ctx, func(out io.Reader) error {
+				_, err := io.Copy(io.Discard, out)
+				return err
+			}, "sh", "-c", "sleep 5 & sleep 5")
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected a deadline error, got %v", err)
+		}
+	})
+}
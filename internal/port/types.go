@@ -1,28 +1,106 @@
 package port
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
 
 // Protocol represents a network protocol.
 type Protocol string
 
 const (
-	TCP Protocol = "TCP"
-	UDP Protocol = "UDP"
+	TCP  Protocol = "TCP"
+	UDP  Protocol = "UDP"
+	Unix Protocol = "UNIX" // Unix domain socket; Path is set instead of Port
+)
+
+// Family is the IP address family a socket accepts connections on.
+type Family string
+
+const (
+	IPv4      Family = "IPv4"
+	IPv6      Family = "IPv6"
+	DualStack Family = "dual" // IPv6 socket that also accepts IPv4
 )
 
 // PortEntry represents a single port being used by a process.
 type PortEntry struct {
-	Port     int
-	Protocol Protocol
-	PID      int
-	Process  string // short process name
-	User     string // owner
-	Command  string // full command path
-	State    string // LISTEN, ESTABLISHED, etc.
-	FD       string // file descriptor
+	Port       int
+	Protocol   Protocol
+	PID        int
+	Process    string // short process name
+	User       string // owner
+	Command    string // full command path
+	State      State  // LISTEN, ESTABLISHED, etc.
+	FD         string // file descriptor
+	LocalAddr  string // bind address, "*" for all interfaces
+	Family     Family // IPv4, IPv6 or dual-stack
+	Zone       string // IPv6 zone or bound interface, if any
+	RemoteAddr string // peer address for connected sockets
+	RemotePort int    // peer port, 0 if not connected
+	Path       string // Unix socket path, "@name" for abstract sockets
+	NetNS      uint64 // network namespace inode, 0 if unknown
+
+	// Container is the container the process runs in; it is only set by
+	// AttachContainers.
+	Container Container
+
+	// PPID and StartTime are filled in, along with the full Command, by
+	// the scanner's process enrichment. They are zero if the process
+	// could not be inspected.
+	PPID      int
+	StartTime time.Time
 }
 
 // String returns a human-readable representation of the entry.
 func (e PortEntry) String() string {
+	if e.Protocol == Unix {
+		return fmt.Sprintf("%s (PID %d, %s)", e.Path, e.PID, e.Process)
+	}
 	return fmt.Sprintf("%d/%s (PID %d, %s)", e.Port, e.Protocol, e.PID, e.Process)
 }
+
+// Bind returns the bind address with its zone, e.g. "127.0.0.1", "*" or
+// "fe80::1%en0".
+func (e PortEntry) Bind() string {
+	if e.Zone != "" {
+		return e.LocalAddr + "%" + e.Zone
+	}
+	return e.LocalAddr
+}
+
+// Remote returns the peer as "host:port", or "" for sockets that are not
+// connected.
+func (e PortEntry) Remote() string {
+	if e.RemoteAddr == "" {
+		return ""
+	}
+	return net.JoinHostPort(e.RemoteAddr, strconv.Itoa(e.RemotePort))
+}
+
+// BindSummary describes who can reach the socket, e.g.
+// "* (all interfaces, IPv4+IPv6)" or "127.0.0.1 (loopback, IPv4)".
+func (e PortEntry) BindSummary() string {
+	bind := e.Bind()
+	if bind == "" {
+		return ""
+	}
+
+	family := string(e.Family)
+	if e.Family == DualStack {
+		family = "IPv4+IPv6"
+	}
+
+	switch {
+	case e.LocalAddr == "*":
+		return fmt.Sprintf("%s (all interfaces, %s)", bind, family)
+	case net.ParseIP(e.LocalAddr).IsLoopback():
+		return fmt.Sprintf("%s (loopback, %s)", bind, family)
+	case family == "":
+		return bind
+	default:
+		return fmt.Sprintf("%s (%s)", bind, family)
+	}
+}
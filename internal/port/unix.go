@@ -0,0 +1,244 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UnixScanner is implemented by scanners that can also list Unix domain
+// sockets. Only sockets bound to a path or an abstract name are returned.
+// Listening stream sockets and bound, unconnected datagram sockets are
+// reported as LISTEN; accepted connections carry the listener's path and
+// are reported as ESTABLISHED.
+type UnixScanner interface {
+	ListUnixSockets(ctx context.Context) ([]PortEntry, error)
+}
+
+// ListUnixSockets lists Unix sockets with scanner, or returns an error if
+// the backend does not support them.
+func ListUnixSockets(ctx context.Context, scanner Scanner) ([]PortEntry, error) {
+	us, ok := scanner.(UnixScanner)
+	if !ok {
+		return nil, fmt.Errorf("backend does not support Unix sockets")
+	}
+	return us.ListUnixSockets(ctx)
+}
+
+// FindByPath returns the Unix socket entries bound to path.
+func FindByPath(ctx context.Context, scanner Scanner, path string) ([]PortEntry, error) {
+	entries, err := ListUnixSockets(ctx, scanner)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []PortEntry
+	for _, e := range entries {
+		if e.Path == path {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// IsUnixPath reports whether a command-line argument names a Unix socket
+// rather than a port: an absolute or relative path, or an abstract
+// "@name".
+func IsUnixPath(arg string) bool {
+	return strings.ContainsRune(arg, '/') || strings.HasPrefix(arg, "@")
+}
+
+// Unix socket constants from include/net/af_unix.h and linux/net.h.
+const (
+	unixFlagAcceptCon = 0x10000 // __SO_ACCEPTCON: socket is listening
+	unixTypeDgram     = 2
+	unixStateConn     = 3 // SS_CONNECTED
+)
+
+// parseProcNetUnix parses the contents of /proc/net/unix. Each line after
+// the header has fields:
+// Num RefCount Protocol Flags Type St Inode [Path]
+// Sockets without a path are skipped.
+func parseProcNetUnix(output string) []socketInfo {
+	lines := strings.Split(output, "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	var socks []socketInfo
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			continue
+		}
+		typ, err := strconv.ParseUint(fields[4], 16, 16)
+		if err != nil {
+			continue
+		}
+		st, err := strconv.ParseUint(fields[5], 16, 8)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// Paths may contain spaces, so take the rest of the line after
+		// the inode. Abstract names are printed with a leading "@".
+		rest := line
+		for range 7 {
+			rest = strings.TrimLeft(rest, " ")
+			rest = rest[strings.IndexByte(rest, ' '):]
+		}
+		path := strings.TrimSpace(rest)
+
+		state := StateClose
+		switch {
+		case flags&unixFlagAcceptCon != 0:
+			state = StateListen
+		case st == unixStateConn:
+			state = StateEstablished
+		case typ == unixTypeDgram:
+			state = StateListen
+		}
+
+		socks = append(socks, socketInfo{
+			proto: Unix,
+			state: state,
+			inode: inode,
+			path:  path,
+		})
+	}
+	return socks
+}
+
+// ListUnixSockets returns the named Unix sockets from /proc/net/unix of
+// every visible network namespace.
+func (s *ProcScanner) ListUnixSockets(ctx context.Context) ([]PortEntry, error) {
+	socks := make(map[uint64]socketInfo)
+	for i, dir := range s.netnsDirs() {
+		data, err := os.ReadFile(filepath.Join(dir.path, "unix"))
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("failed to read Unix sockets: %w", err)
+			}
+			continue
+		}
+		for _, sock := range parseProcNetUnix(string(data)) {
+			sock.netns = dir.netns
+			socks[sock.inode] = sock
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.joinOwners(ctx, socks)
+	if err != nil {
+		return nil, err
+	}
+	// /proc/net/unix carries no owner, so report the process owner.
+	for i := range entries {
+		entries[i].User = s.processUser(entries[i].PID)
+	}
+	return entries, nil
+}
+
+// ListUnixSockets returns the named Unix sockets. sock_diag can dump Unix
+// sockets too, but /proc/net/unix carries the same data and is always
+// readable.
+func (s *NetlinkScanner) ListUnixSockets(ctx context.Context) ([]PortEntry, error) {
+	return s.procs.ListUnixSockets(ctx)
+}
+
+// ListUnixSockets returns the named Unix sockets reported by lsof -U.
+func (s *LsofScanner) ListUnixSockets(ctx context.Context) ([]PortEntry, error) {
+	var entries []PortEntry
+	err := streamOutput(ctx, s.runner, func(r io.Reader) error {
+		var err error
+		entries, err = parseLsofFields(r, (*lsofFile).unixEntry, nil)
+		return err
+	}, "lsof", "+c", "0", "-F", lsofFieldSpec, "-U", "-P", "-n")
+	if err != nil && !lsofNoMatch(err) {
+		return nil, fmt.Errorf("failed to run lsof: %w", err)
+	}
+
+	for i := range entries {
+		if uid, err := strconv.Atoi(entries[i].User); err == nil {
+			entries[i].User = s.users.name(uid)
+		}
+	}
+	s.procs.enrichFromPs(ctx, s.runner, entries)
+	return entries, nil
+}
+
+// ListUnixSockets returns the named Unix sockets reported by ss -x.
+func (s *SsScanner) ListUnixSockets(ctx context.Context) ([]PortEntry, error) {
+	out, err := s.runner.Run(ctx, "ss", "-xapnHe")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ss: %w", err)
+	}
+
+	// ss does not report a UID for Unix sockets, so report the process
+	// owner.
+	entries := ParseSsUnixOutput(string(out))
+	for i := range entries {
+		if uid, ok := procUID("/proc", entries[i].PID); ok {
+			entries[i].User = s.users.name(uid)
+		}
+	}
+	s.procs.enrichFromPs(ctx, s.runner, entries)
+	return entries, nil
+}
+
+// ParseSsUnixOutput parses the output from ss -xapnHe.
+// Each line has fields:
+// Netid State Recv-Q Send-Q Path Inode Peer PeerInode users:((...)) ...
+// Unnamed sockets ("*" as the path) are skipped. The User field is left
+// empty since ss does not print one for Unix sockets.
+func ParseSsUnixOutput(output string) []PortEntry {
+	var entries []PortEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 || !strings.HasPrefix(fields[0], "u_") {
+			continue
+		}
+
+		path := fields[4]
+		if path == "*" {
+			continue
+		}
+
+		state, ok := ParseState(fields[1])
+		if !ok {
+			continue
+		}
+		if fields[0] == "u_dgr" && state == StateClose {
+			state = StateListen
+		}
+
+		for _, u := range parseSsUsers(line) {
+			entries = append(entries, PortEntry{
+				Process:  u.name,
+				PID:      u.pid,
+				FD:       u.fd,
+				Protocol: Unix,
+				State:    state,
+				Command:  u.name,
+				Path:     path,
+			})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,228 @@
+package port
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseProcNetUnix(t *testing.T) {
+	socks := parseProcNetUnix(procUnixFixture)
+	if len(socks) != 4 {
+		t.Fatalf("expected 4 named sockets, got %d", len(socks))
+	}
+
+	tests := []struct {
+		idx   int
+		path  string
+		state State
+		inode uint64
+	}{
+		{0, "/run/postgresql/.s.PGSQL.5432", StateListen, 4001},
+		{1, "/run/postgresql/.s.PGSQL.5432", StateEstablished, 4002},
+		{2, "/run/my app/notify.sock", StateListen, 4003},
+		{3, "@/tmp/.X11-unix/X0", StateListen, 4004},
+	}
+
+	for _, tt := range tests {
+		s := socks[tt.idx]
+		if s.path != tt.path {
+			t.Errorf("[%d] path: got %q, want %q", tt.idx, s.path, tt.path)
+		}
+		if s.state != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, s.state, tt.state)
+		}
+		if s.inode != tt.inode {
+			t.Errorf("[%d] inode: got %d, want %d", tt.idx, s.inode, tt.inode)
+		}
+		if s.proto != Unix {
+			t.Errorf("[%d] proto: got %q, want UNIX", tt.idx, s.proto)
+		}
+	}
+}
+
+func TestProcScanner_ListUnixSockets(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := s.ListUnixSockets(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx     int
+		process string
+		pid     int
+		path    string
+		fd      string
+		state   State
+	}{
+		{0, "postgres", 200, "/run/postgresql/.s.PGSQL.5432", "8u", StateListen},
+		{1, "postgres", 200, "/run/postgresql/.s.PGSQL.5432", "9u", StateEstablished},
+		{2, "node", 300, "/run/my app/notify.sock", "22u", StateListen},
+		{3, "Xorg", 500, "@/tmp/.X11-unix/X0", "3u", StateListen},
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.Process != tt.process || e.PID != tt.pid {
+			t.Errorf("[%d] process: got %s/%d, want %s/%d", tt.idx, e.Process, e.PID, tt.process, tt.pid)
+		}
+		if e.Path != tt.path {
+			t.Errorf("[%d] path: got %q, want %q", tt.idx, e.Path, tt.path)
+		}
+		if e.FD != tt.fd {
+			t.Errorf("[%d] fd: got %q, want %q", tt.idx, e.FD, tt.fd)
+		}
+		if e.State != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, e.State, tt.state)
+		}
+		if e.Protocol != Unix || e.Port != 0 {
+			t.Errorf("[%d] expected a Unix entry without a port, got %s/%d", tt.idx, e.Protocol, e.Port)
+		}
+	}
+}
+
+func TestFindByPath(t *testing.T) {
+	s := NewProcScanner(writeProcFixture(t))
+
+	entries, err := FindByPath(context.Background(), s, "/run/postgresql/.s.PGSQL.5432")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.PID != 200 {
+			t.Errorf("unexpected entry: %s", e)
+		}
+	}
+}
+
+func TestListUnixSockets_Unsupported(t *testing.T) {
+	s := NewNetstatScanner(&MockCmdRunner{})
+
+	if _, err := ListUnixSockets(context.Background(), s); err == nil {
+		t.Fatal("expected error for backend without Unix socket support")
+	}
+}
+
+func TestParseLsofUnixOutput(t *testing.T) {
+	input := `p1234
+cdockerd
+u0
+Lroot
+f5
+au
+tunix
+n/run/docker.sock type=STREAM
+TST=LISTEN
+f6
+au
+tunix
+ntype=STREAM
+TST=CONNECTED
+f7
+au
+tunix
+n/run/docker.sock type=STREAM
+TST=CONNECTED
+p321
+csystemd-journald
+u0
+f3
+au
+tunix
+n/run/systemd/journal/dev-log type=DGRAM
+TST=UNCONNECTED
+f8
+au
+tIPv4
+PTCP
+n*:80
+TST=LISTEN
+`
+
+	entries := ParseLsofUnixOutput(input)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		idx   int
+		pid   int
+		user  string
+		path  string
+		state State
+	}{
+		{0, 1234, "root", "/run/docker.sock", StateListen},
+		{1, 1234, "root", "/run/docker.sock", StateEstablished},
+		{2, 321, "0", "/run/systemd/journal/dev-log", StateListen},
+	}
+
+	for _, tt := range tests {
+		e := entries[tt.idx]
+		if e.PID != tt.pid || e.User != tt.user {
+			t.Errorf("[%d] owner: got %d/%s, want %d/%s", tt.idx, e.PID, e.User, tt.pid, tt.user)
+		}
+		if e.Path != tt.path {
+			t.Errorf("[%d] path: got %q, want %q", tt.idx, e.Path, tt.path)
+		}
+		if e.State != tt.state {
+			t.Errorf("[%d] state: got %q, want %q", tt.idx, e.State, tt.state)
+		}
+		if e.Protocol != Unix {
+			t.Errorf("[%d] protocol: got %q, want UNIX", tt.idx, e.Protocol)
+		}
+	}
+}
+
+func TestParseSsUnixOutput(t *testing.T) {
+	input := `u_str LISTEN 0      4096   /run/docker.sock 3622  * 0     users:(("dockerd",pid=1234,fd=5)) <-> ino:1114179 dev:0/65024 peers:
+u_str ESTAB  0      0                     * 22445 * 22446 users:(("bash",pid=12409,fd=10)) <->
+u_str ESTAB  0      0      /run/docker.sock 22447 * 22448 users:(("dockerd",pid=1234,fd=9)) <->
+u_dgr UNCONN 0      0      /run/systemd/notify 1500 * 0 users:(("systemd",pid=1,fd=14)) <->
+`
+
+	entries := ParseSsUnixOutput(input)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	wantPaths := []string{"/run/docker.sock", "/run/docker.sock", "/run/systemd/notify"}
+	wantStates := []State{StateListen, StateEstablished, StateListen}
+	wantFDs := []string{"5u", "9u", "14u"}
+	for i, e := range entries {
+		if e.Path != wantPaths[i] {
+			t.Errorf("[%d] path: got %q, want %q", i, e.Path, wantPaths[i])
+		}
+		if e.State != wantStates[i] {
+			t.Errorf("[%d] state: got %q, want %q", i, e.State, wantStates[i])
+		}
+		if e.FD != wantFDs[i] {
+			t.Errorf("[%d] fd: got %q, want %q", i, e.FD, wantFDs[i])
+		}
+	}
+}
+
+func TestIsUnixPath(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{"3000", false},
+		{"/run/docker.sock", true},
+		{"./app.sock", true},
+		{"@/tmp/.X11-unix/X0", true},
+		{"postgres", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsUnixPath(tt.arg); got != tt.want {
+			t.Errorf("IsUnixPath(%q): got %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package port
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+// userCache resolves numeric UIDs to login names, remembering the answers
+// so repeated scans do not hit the user database again.
+type userCache struct {
+	mu    sync.Mutex
+	names map[int]string
+	// remote is set when the UIDs come from another host, whose users
+	// the local database does not know. They are kept as numbers until
+	// the process table supplies the names.
+	remote bool
+}
+
+// name resolves a UID to a login name, falling back to the number.
+func (c *userCache) name(uid int) string {
+	if c.remote {
+		return strconv.Itoa(uid)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name, ok := c.names[uid]; ok {
+		return name
+	}
+	if c.names == nil {
+		c.names = make(map[int]string)
+	}
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	c.names[uid] = name
+	return name
+}
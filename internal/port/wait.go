@@ -0,0 +1,142 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// WaitState is a state of a port that whport wait waits for.
+type WaitState string
+
+const (
+	// WaitListening is reached when a process listens on the port.
+	WaitListening WaitState = "listening"
+	// WaitFree is reached when nothing listens on the port, although
+	// connections may linger.
+	WaitFree WaitState = "free"
+	// WaitClosed is reached when no process holds a socket on the port.
+	// Sockets no process owns, such as those in TIME_WAIT, are not seen.
+	WaitClosed WaitState = "closed"
+)
+
+// WaitOptions selects what CheckPort checks for and how WaitForPort polls.
+type WaitOptions struct {
+	Until       WaitState
+	Process     string        // only count sockets of processes whose name contains this
+	Interval    time.Duration // time between checks
+	ScanTimeout time.Duration // bounds each scan; 0 for no bound but ctx
+
+	// Connect, if set, must succeed for a listener to count toward
+	// WaitListening. It returns an error for listeners it cannot reach.
+	Connect func(ctx context.Context, e PortEntry) (bool, error)
+}
+
+// ScanError is the error CheckPort returns when the scan itself fails.
+type ScanError struct {
+	Err error
+}
+
+func (e *ScanError) Error() string { return e.Err.Error() }
+func (e *ScanError) Unwrap() error { return e.Err }
+
+// CheckPort looks up the sockets bound to port and reports whether the
+// port is in the state opts waits for, along with the listener found, if
+// any. It returns a *ScanError if the scan fails, and fails if Connect
+// fails for every listener.
+func CheckPort(ctx context.Context, scanner Scanner, port int, opts WaitOptions) (bool, *PortEntry, error) {
+	entries, err := scanner.FindByPort(ctx, port)
+	if err != nil {
+		return false, nil, &ScanError{Err: err}
+	}
+
+	var listeners []PortEntry
+	sockets := 0
+	for _, e := range boundTo(entries, port) {
+		if opts.Process != "" && !strings.Contains(strings.ToLower(e.Process), strings.ToLower(opts.Process)) {
+			continue
+		}
+		sockets++
+		if e.State == StateListen {
+			listeners = append(listeners, e)
+		}
+	}
+
+	switch opts.Until {
+	case WaitFree:
+		return len(listeners) == 0, nil, nil
+	case WaitClosed:
+		return sockets == 0, nil, nil
+	}
+
+	if len(listeners) == 0 {
+		return false, nil, nil
+	}
+	if opts.Connect == nil {
+		return true, &listeners[0], nil
+	}
+	var connectErr error
+	reachable := false
+	for i := range listeners {
+		ok, err := opts.Connect(ctx, listeners[i])
+		if err != nil {
+			connectErr = err
+			continue
+		}
+		if ok {
+			return true, &listeners[i], nil
+		}
+		reachable = true
+	}
+	// Waiting longer cannot help if no listener can be reached.
+	if !reachable {
+		return false, &listeners[0], connectErr
+	}
+	return false, &listeners[0], nil
+}
+
+// WaitForPort checks the port every opts.Interval until it reaches the
+// state opts waits for, and returns the listener found, if any. A failed
+// or timed out scan is retried at the next check. If ctx is done first,
+// it returns ctx's error, joined with the *ScanError of the last check if
+// that scan failed.
+func WaitForPort(ctx context.Context, scanner Scanner, port int, opts WaitOptions) (*PortEntry, error) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		done, listener, err := checkPortWithin(ctx, scanner, port, opts)
+		if err == nil && done {
+			return listener, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Join(ctx.Err(), lastErr)
+		}
+		var scanErr *ScanError
+		switch {
+		case errors.As(err, &scanErr):
+			lastErr = err
+		case err != nil:
+			return nil, err
+		default:
+			lastErr = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(ctx.Err(), lastErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkPortWithin runs CheckPort with its scan bounded by opts.ScanTimeout.
+func checkPortWithin(ctx context.Context, scanner Scanner, port int, opts WaitOptions) (bool, *PortEntry, error) {
+	if opts.ScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.ScanTimeout)
+		defer cancel()
+	}
+	return CheckPort(ctx, scanner, port, opts)
+}
@@ -0,0 +1,232 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	lsofWaitKey = "lsof +c 0 -F pcuLfatPnT -i:3000 -P -n"
+
+	nodeListening = "p5678\ncnode\nu501\nf8\nau\ntIPv4\nPTCP\nn*:3000\nTST=LISTEN\n"
+	nodeConnected = "p5678\ncnode\nu501\nf9\nau\ntIPv4\nPTCP\nn127.0.0.1:3000->127.0.0.1:51000\nTST=CLOSE_WAIT\n"
+	curlConnected = "p812\nccurl\nu501\nf5\nau\ntIPv4\nPTCP\nn127.0.0.1:51000->127.0.0.1:3000\nTST=ESTABLISHED\n"
+)
+
+// waitScanner returns an lsof scanner that finds the given output on
+// port 3000.
+func waitScanner(output string) Scanner {
+	return NewLsofScanner(&MultiMockCmdRunner{
+		Responses: map[string]MockResponse{lsofWaitKey: {Output: []byte(output)}},
+	})
+}
+
+func TestCheckPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		opts     WaitOptions
+		wantDone bool
+		wantPID  int
+	}{
+		{"listening", nodeListening, WaitOptions{Until: WaitListening}, true, 5678},
+		{"not listening yet", "", WaitOptions{Until: WaitListening}, false, 0},
+		{"connection is not listening", nodeConnected, WaitOptions{Until: WaitListening}, false, 0},
+		{"listening by process", nodeListening, WaitOptions{Until: WaitListening, Process: "NODE"}, true, 5678},
+		{"listening by other process", nodeListening, WaitOptions{Until: WaitListening, Process: "python"}, false, 0},
+		{"free", "", WaitOptions{Until: WaitFree}, true, 0},
+		{"free with lingering connection", nodeConnected, WaitOptions{Until: WaitFree}, true, 0},
+		{"not free", nodeListening + nodeConnected, WaitOptions{Until: WaitFree}, false, 0},
+		{"free of process", nodeListening, WaitOptions{Until: WaitFree, Process: "python"}, true, 0},
+		{"closed", "", WaitOptions{Until: WaitClosed}, true, 0},
+		{"not closed", nodeConnected, WaitOptions{Until: WaitClosed}, false, 0},
+		{"client connection to the port elsewhere", curlConnected, WaitOptions{Until: WaitClosed}, true, 0},
+		{"closed for process", nodeConnected, WaitOptions{Until: WaitClosed, Process: "python"}, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, listener, err := CheckPort(context.Background(), waitScanner(tt.output), 3000, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done: got %v, want %v", done, tt.wantDone)
+			}
+			pid := 0
+			if listener != nil && done {
+				pid = listener.PID
+			}
+			if pid != tt.wantPID {
+				t.Errorf("listener PID: got %d, want %d", pid, tt.wantPID)
+			}
+		})
+	}
+}
+
+func TestCheckPort_Connect(t *testing.T) {
+	errUnreachable := errors.New("unreachable")
+	twoListeners := nodeListening +
+		"p9000\ncdocker-proxy\nu0\nf4\nau\ntIPv6\nPTCP\nn*:3000\nTST=LISTEN\n"
+
+	tests := []struct {
+		name     string
+		output   string
+		connect  func(ctx context.Context, e PortEntry) (bool, error)
+		wantDone bool
+		wantPID  int
+		wantErr  bool
+	}{
+		{
+			name:     "connects",
+			output:   nodeListening,
+			connect:  func(context.Context, PortEntry) (bool, error) { return true, nil },
+			wantDone: true,
+			wantPID:  5678,
+		},
+		{
+			name:    "refused",
+			output:  nodeListening,
+			connect: func(context.Context, PortEntry) (bool, error) { return false, nil },
+		},
+		{
+			name:    "unreachable",
+			output:  nodeListening,
+			connect: func(context.Context, PortEntry) (bool, error) { return false, errUnreachable },
+			wantErr: true,
+		},
+		{
+			name:   "second listener reachable",
+			output: twoListeners,
+			connect: func(_ context.Context, e PortEntry) (bool, error) {
+				if e.PID == 5678 {
+					return false, errUnreachable
+				}
+				return true, nil
+			},
+			wantDone: true,
+			wantPID:  9000,
+		},
+		{
+			name:   "second listener refuses",
+			output: twoListeners,
+			connect: func(_ context.Context, e PortEntry) (bool, error) {
+				if e.PID == 5678 {
+					return false, errUnreachable
+				}
+				return false, nil
+			},
+		},
+		{
+			name:    "not listening yet",
+			output:  "",
+			connect: func(context.Context, PortEntry) (bool, error) { return false, errUnreachable },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := WaitOptions{Until: WaitListening, Connect: tt.connect}
+			done, listener, err := CheckPort(context.Background(), waitScanner(tt.output), 3000, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done: got %v, want %v", done, tt.wantDone)
+			}
+			if tt.wantDone && (listener == nil || listener.PID != tt.wantPID) {
+				t.Errorf("listener: got %+v, want PID %d", listener, tt.wantPID)
+			}
+		})
+	}
+}
+
+func TestCheckPort_ScanError(t *testing.T) {
+	scanner := NewLsofScanner(&MultiMockCmdRunner{
+		Responses: map[string]MockResponse{
+			lsofWaitKey:          {Err: errors.New("lsof crashed")},
+			"lsof -i:3000 -P -n": {Err: errors.New("lsof crashed")},
+		},
+	})
+	if _, _, err := CheckPort(context.Background(), scanner, 3000, WaitOptions{Until: WaitFree}); err == nil {
+		t.Error("expected an error when the scan fails")
+	}
+}
+
+func TestWaitForPort(t *testing.T) {
+	opts := WaitOptions{Until: WaitListening, Interval: time.Millisecond}
+	listener, err := WaitForPort(context.Background(), waitScanner(nodeListening), 3000, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if listener == nil || listener.PID != 5678 {
+		t.Errorf("listener: got %+v, want PID 5678", listener)
+	}
+}
+
+func TestWaitForPort_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	opts := WaitOptions{Until: WaitFree, Interval: time.Millisecond}
+	_, err := WaitForPort(ctx, waitScanner(nodeListening), 3000, opts)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWaitForPort_ConnectUnreachable(t *testing.T) {
+	errUnreachable := errors.New("unreachable")
+	opts := WaitOptions{
+		Until:    WaitListening,
+		Interval: time.Millisecond,
+		Connect:  func(context.Context, PortEntry) (bool, error) { return false, errUnreachable },
+	}
+	// Fails at once rather than waiting for a connection that cannot happen.
+	_, err := WaitForPort(context.Background(), waitScanner(nodeListening), 3000, opts)
+	if !errors.Is(err, errUnreachable) {
+		t.Errorf("expected the connect error, got %v", err)
+	}
+}
+
+// flakyScanner fails its first scans and then finds the wrapped
+// scanner's output.
+type flakyScanner struct {
+	Scanner
+	failures int
+}
+
+func (s *flakyScanner) FindByPort(ctx context.Context, port int) ([]PortEntry, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, context.DeadlineExceeded
+	}
+	return s.Scanner.FindByPort(ctx, port)
+}
+
+func TestWaitForPort_RetriesFailedScan(t *testing.T) {
+	scanner := &flakyScanner{Scanner: waitScanner(nodeListening), failures: 1}
+	opts := WaitOptions{Until: WaitListening, Interval: time.Millisecond}
+	listener, err := WaitForPort(context.Background(), scanner, 3000, opts)
+	if err != nil {
+		t.Fatalf("expected the failed scan to be retried, got %v", err)
+	}
+	if listener == nil || listener.PID != 5678 {
+		t.Errorf("listener: got %+v, want PID 5678", listener)
+	}
+}
+
+func TestWaitForPort_TimeoutAfterFailedScans(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	scanner := &flakyScanner{Scanner: waitScanner(nodeListening), failures: 1 << 30}
+	opts := WaitOptions{Until: WaitListening, Interval: time.Millisecond}
+	_, err := WaitForPort(ctx, scanner, 3000, opts)
+	var scanErr *ScanError
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &scanErr) {
+		t.Errorf("expected the deadline joined with the last scan error, got %v", err)
+	}
+}
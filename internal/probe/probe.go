@@ -0,0 +1,386 @@
+// Package probe identifies the protocol a TCP listener speaks by
+// connecting to it. It recognises servers that greet first (SSH, MySQL)
+// and then tries TLS, HTTP, Redis and PostgreSQL in turn, each on a fresh
+// connection with a short timeout.
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+// DefaultTimeout bounds each connection attempt and each read. A listener
+// that answers none of the probes takes a few times this long.
+const DefaultTimeout = 500 * time.Millisecond
+
+// bannerWait is how long to wait for a server that greets first. Most
+// servers wait for the client, so this is paid on nearly every probe.
+const bannerWait = 200 * time.Millisecond
+
+// maxParallel limits how many listeners are probed at once.
+const maxParallel = 16
+
+// Protocols reported in Result.Protocol.
+const (
+	HTTP       = "http"
+	HTTPS      = "https"
+	TLS        = "tls"
+	SSH        = "ssh"
+	Redis      = "redis"
+	PostgreSQL = "postgresql"
+	MySQL      = "mysql"
+)
+
+// Result describes what a listener answered.
+type Result struct {
+	Protocol string
+	App      string // server software, e.g. "nginx/1.25.3" or "OpenSSH_9.6"
+	Status   string // HTTP status line, or a server's error message
+
+	// TLSSubject and TLSExpiry describe the certificate of a TLS
+	// listener.
+	TLSSubject string
+	TLSExpiry  time.Time
+}
+
+// String returns the protocol and the application, as shown in the
+// PROTOCOL/APP column, e.g. "http (nginx/1.25.3)".
+func (r *Result) String() string {
+	if r == nil {
+		return "-"
+	}
+	if r.App == "" {
+		return r.Protocol
+	}
+	return fmt.Sprintf("%s (%s)", r.Protocol, r.App)
+}
+
+// Target returns the address to connect to for a listener, or false for
+// sockets that cannot be probed from here: anything but TCP listeners,
+// and listeners in other network namespaces. Wildcard binds are reached
+// over loopback.
+func Target(e port.PortEntry) (string, bool) {
+	if e.Protocol != port.TCP || e.State != port.StateListen || e.Port == 0 {
+		return "", false
+	}
+	if e.NetNS != 0 && e.NetNS != port.SelfNetNS() {
+		return "", false
+	}
+
+	host := e.LocalAddr
+	switch host {
+	case "*", "0.0.0.0", "":
+		host = "127.0.0.1"
+		if e.Family == port.IPv6 {
+			host = "::1"
+		}
+	case "::":
+		host = "::1"
+	default:
+		if e.Zone != "" {
+			host += "%" + e.Zone
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(e.Port)), true
+}
+
+// Entries probes the listeners among entries concurrently and returns the
+// results in the same order. Entries that cannot be probed or that were
+// not identified have a nil result.
+func Entries(ctx context.Context, entries []port.PortEntry, timeout time.Duration) []*Result {
+	results := make([]*Result, len(entries))
+	byAddr := make(map[string][]int)
+	for i, e := range entries {
+		if addr, ok := Target(e); ok {
+			byAddr[addr] = append(byAddr[addr], i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallel)
+	for addr, idx := range byAddr {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			r, _ := Probe(ctx, addr, timeout)
+			for _, i := range idx {
+				results[i] = r
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Probe identifies the protocol spoken at addr. It returns nil without
+// error if the listener accepted connections but answered none of the
+// probes, and an error if it could not be reached at all.
+func Probe(ctx context.Context, addr string, timeout time.Duration) (*Result, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	p := &prober{addr: addr, timeout: timeout}
+
+	r, err := p.banner(ctx)
+	if r != nil || err != nil {
+		return r, err
+	}
+	for _, try := range []func(context.Context) *Result{p.tryTLS, p.tryHTTP, p.tryPostgres} {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if r := try(ctx); r != nil {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// prober holds what every probe of one listener needs.
+type prober struct {
+	addr    string
+	timeout time.Duration
+}
+
+// dial connects to the listener with a deadline of d for the whole
+// exchange.
+func (p *prober) dial(ctx context.Context, d time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(d)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+// banner waits briefly for a greeting from servers that speak first.
+func (p *prober) banner(ctx context.Context) (*Result, error) {
+	conn, err := p.dial(ctx, min(bannerWait, p.timeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", p.addr, err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 512)
+	n, _ := io.ReadAtLeast(conn, buf, 5)
+	return parseBanner(buf[:n]), nil
+}
+
+// parseBanner recognises an SSH identification string or a MySQL
+// handshake packet.
+func parseBanner(b []byte) *Result {
+	if bytes.HasPrefix(b, []byte("SSH-")) {
+		line, _, _ := bytes.Cut(b, []byte("\n"))
+		line = bytes.TrimRight(line, "\r")
+		// SSH-protoversion-softwareversion SP comments
+		_, app, _ := strings.Cut(strings.TrimPrefix(string(line), "SSH-"), "-")
+		return &Result{Protocol: SSH, App: app}
+	}
+
+	// A MySQL packet is a 3-byte length and a sequence number of 0,
+	// followed by a version 10 handshake or an error packet.
+	if len(b) < 5 || b[3] != 0 {
+		return nil
+	}
+	size := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	if size == 0 || size+4 > len(b) {
+		return nil
+	}
+	payload := b[4 : 4+size]
+	switch payload[0] {
+	case 10:
+		version, _, ok := bytes.Cut(payload[1:], []byte{0})
+		if !ok {
+			return nil
+		}
+		return &Result{Protocol: MySQL, App: mysqlApp(string(version))}
+	case 0xff:
+		// Error packet: 2-byte code, then the message.
+		if len(payload) < 3 {
+			return nil
+		}
+		return &Result{Protocol: MySQL, Status: string(payload[3:])}
+	}
+	return nil
+}
+
+// mysqlApp names the server from its version string. MariaDB prefixes
+// its version with "5.5.5-" for old clients.
+func mysqlApp(version string) string {
+	version = strings.TrimPrefix(version, "5.5.5-")
+	if v, _, ok := strings.Cut(version, "-MariaDB"); ok {
+		return "MariaDB " + v
+	}
+	return "MySQL " + version
+}
+
+// tryTLS attempts a TLS handshake, accepting any certificate, and then
+// asks for / over HTTP to tell HTTPS from other TLS services.
+func (p *prober) tryTLS(ctx context.Context) *Result {
+	conn, err := p.dial(ctx, 2*p.timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	var leaf *x509.Certificate
+	cfg := &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"http/1.1"},
+		// Record the certificate even if the handshake fails later, for
+		// example because the server wants a client certificate.
+		VerifyPeerCertificate: func(raw [][]byte, _ [][]*x509.Certificate) error {
+			if len(raw) > 0 {
+				leaf, _ = x509.ParseCertificate(raw[0])
+			}
+			return nil
+		},
+	}
+	tc := tls.Client(conn, cfg)
+	err = tc.HandshakeContext(ctx)
+	if leaf == nil {
+		return nil
+	}
+
+	r := &Result{Protocol: TLS, TLSSubject: leaf.Subject.String(), TLSExpiry: leaf.NotAfter}
+	if err != nil {
+		return r
+	}
+	if resp := httpGet(tc, p.addr); resp != nil {
+		r.Protocol = HTTPS
+		r.App = resp.Header.Get("Server")
+		r.Status = statusLine(resp)
+	}
+	return r
+}
+
+// tryHTTP sends a plain HTTP/1.0 request. Redis parses the request line as
+// a GET command with the wrong number of arguments and answers with an
+// error, which identifies it too. The request has no Host header, since
+// Redis treats one as an attack and drops the connection.
+func (p *prober) tryHTTP(ctx context.Context) *Result {
+	conn, err := p.dial(ctx, p.timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "GET / HTTP/1.0\r\nUser-Agent: whport\r\n\r\n"); err != nil {
+		return nil
+	}
+	br := bufio.NewReader(conn)
+	head, _ := br.Peek(5)
+	switch {
+	case bytes.Equal(head, []byte("HTTP/")):
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			return nil
+		}
+		resp.Body.Close()
+		return &Result{Protocol: HTTP, App: resp.Header.Get("Server"), Status: statusLine(resp)}
+	case len(head) > 0 && (head[0] == '-' || head[0] == '+'):
+		return p.tryRedis(ctx)
+	}
+	return nil
+}
+
+// tryRedis asks a Redis server for its version. Servers that require a
+// password refuse, and are reported without one.
+func (p *prober) tryRedis(ctx context.Context) *Result {
+	r := &Result{Protocol: Redis}
+	conn, err := p.dial(ctx, p.timeout)
+	if err != nil {
+		return r
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "INFO server\r\n"); err != nil {
+		return r
+	}
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "-") {
+			r.Status = strings.TrimPrefix(line, "-")
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "redis_version:"); ok {
+			r.App = "Redis " + v
+			break
+		}
+	}
+	return r
+}
+
+// tryPostgres sends an SSLRequest, which a PostgreSQL server answers with a
+// single 'S' or 'N' before any authentication.
+func (p *prober) tryPostgres(ctx context.Context) *Result {
+	conn, err := p.dial(ctx, p.timeout)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	sslRequest := []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+	if _, err := conn.Write(sslRequest); err != nil {
+		return nil
+	}
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(conn, b); err != nil || (b[0] != 'S' && b[0] != 'N') {
+		return nil
+	}
+	// The server now waits for the client. One that says more speaks
+	// some other protocol.
+	conn.SetReadDeadline(time.Now().Add(bannerWait / 4))
+	if _, err := conn.Read(b); !isTimeout(err) {
+		return nil
+	}
+	return &Result{Protocol: PostgreSQL}
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// httpGet requests / over conn and returns the response, or nil if the
+// server does not answer in HTTP.
+func httpGet(conn net.Conn, addr string) *http.Response {
+	req := fmt.Sprintf("GET / HTTP/1.0\r\nHost: %s\r\nUser-Agent: whport\r\n\r\n", addr)
+	if _, err := io.WriteString(conn, req); err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// statusLine formats a response's status line, e.g. "HTTP/1.1 200 OK".
+func statusLine(resp *http.Response) string {
+	return resp.Proto + " " + resp.Status
+}
@@ -0,0 +1,300 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+const testTimeout = 300 * time.Millisecond
+
+// serve starts a loopback server that handles each connection with fn and
+// returns its address.
+func serve(t *testing.T, fn func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fn(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// idle keeps a connection open without answering, until the client goes.
+func idle(conn net.Conn) {
+	io.Copy(io.Discard, conn)
+}
+
+func probe(t *testing.T, addr string) *Result {
+	t.Helper()
+	r, err := Probe(context.Background(), addr, testTimeout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return r
+}
+
+func TestProbe_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	r := probe(t, srv.Listener.Addr().String())
+	if r == nil || r.Protocol != HTTP {
+		t.Fatalf("expected http, got %+v", r)
+	}
+	if r.App != "nginx/1.25.3" {
+		t.Errorf("app: got %q", r.App)
+	}
+	if r.Status != "HTTP/1.0 404 Not Found" {
+		t.Errorf("status: got %q", r.Status)
+	}
+	if r.String() != "http (nginx/1.25.3)" {
+		t.Errorf("label: got %q", r.String())
+	}
+}
+
+// newTLSServer starts an HTTPS test server that does not log the
+// handshakes the probes abandon.
+func newTLSServer(h http.Handler) *httptest.Server {
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	return srv
+}
+
+func TestProbe_HTTPS(t *testing.T) {
+	srv := newTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Caddy")
+	}))
+	defer srv.Close()
+
+	r := probe(t, srv.Listener.Addr().String())
+	if r == nil || r.Protocol != HTTPS {
+		t.Fatalf("expected https, got %+v", r)
+	}
+	if r.App != "Caddy" || r.Status != "HTTP/1.0 200 OK" {
+		t.Errorf("got app %q, status %q", r.App, r.Status)
+	}
+	cert := srv.Certificate()
+	if r.TLSSubject != cert.Subject.String() || !r.TLSExpiry.Equal(cert.NotAfter) {
+		t.Errorf("certificate: got %q expiring %v", r.TLSSubject, r.TLSExpiry)
+	}
+}
+
+func TestProbe_TLS(t *testing.T) {
+	// A TLS service that is not HTTP, with the test server's certificate.
+	srv := newTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	cfg := &tls.Config{Certificates: srv.TLS.Certificates}
+
+	addr := serve(t, func(conn net.Conn) {
+		tc := tls.Server(conn, cfg)
+		if tc.Handshake() == nil {
+			idle(tc)
+		}
+	})
+	r := probe(t, addr)
+	if r == nil || r.Protocol != TLS {
+		t.Fatalf("expected tls, got %+v", r)
+	}
+	if r.TLSSubject == "" || r.TLSExpiry.IsZero() {
+		t.Errorf("expected certificate details, got %+v", r)
+	}
+}
+
+func TestProbe_SSH(t *testing.T) {
+	addr := serve(t, func(conn net.Conn) {
+		io.WriteString(conn, "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n")
+		idle(conn)
+	})
+	r := probe(t, addr)
+	if r == nil || r.Protocol != SSH || r.App != "OpenSSH_9.6p1 Ubuntu-3ubuntu13" {
+		t.Errorf("expected OpenSSH, got %+v", r)
+	}
+}
+
+// mysqlPacket frames a payload as MySQL packet number 0.
+func mysqlPacket(payload string) string {
+	n := len(payload)
+	return string([]byte{byte(n), byte(n >> 8), byte(n >> 16), 0}) + payload
+}
+
+func TestProbe_MySQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting string
+		want     Result
+	}{
+		{"mysql", mysqlPacket("\x0a8.0.36\x00\x08\x00\x00\x00abcdefgh\x00"), Result{Protocol: MySQL, App: "MySQL 8.0.36"}},
+		{"mariadb", mysqlPacket("\x0a5.5.5-10.11.6-MariaDB-1:10.11.6+maria~ubu2204\x00\x01\x00\x00\x00"), Result{Protocol: MySQL, App: "MariaDB 10.11.6"}},
+		{"refused", mysqlPacket("\xff\x6a\x04Host '10.0.0.5' is not allowed to connect"), Result{Protocol: MySQL, Status: "Host '10.0.0.5' is not allowed to connect"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serve(t, func(conn net.Conn) {
+				io.WriteString(conn, tt.greeting)
+				idle(conn)
+			})
+			if r := probe(t, addr); r == nil || *r != tt.want {
+				t.Errorf("got %+v, want %+v", r, tt.want)
+			}
+		})
+	}
+}
+
+// fakeRedis answers inline commands like Redis does.
+func fakeRedis(password bool) func(net.Conn) {
+	return func(conn net.Conn) {
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			switch {
+			case len(fields) == 0:
+			case password:
+				io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			case strings.EqualFold(fields[0], "GET") && len(fields) != 2:
+				io.WriteString(conn, "-ERR wrong number of arguments for 'get' command\r\n")
+			case strings.EqualFold(fields[0], "INFO"):
+				info := "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n"
+				io.WriteString(conn, "$"+strconv.Itoa(len(info))+"\r\n"+info+"\r\n")
+			default:
+				io.WriteString(conn, "-ERR unknown command\r\n")
+			}
+		}
+	}
+}
+
+func TestProbe_Redis(t *testing.T) {
+	r := probe(t, serve(t, fakeRedis(false)))
+	if r == nil || r.Protocol != Redis || r.App != "Redis 7.2.4" {
+		t.Errorf("expected Redis 7.2.4, got %+v", r)
+	}
+
+	r = probe(t, serve(t, fakeRedis(true)))
+	if r == nil || r.Protocol != Redis || r.App != "" || r.Status != "NOAUTH Authentication required." {
+		t.Errorf("expected Redis behind a password, got %+v", r)
+	}
+}
+
+func TestProbe_PostgreSQL(t *testing.T) {
+	addr := serve(t, func(conn net.Conn) {
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		if string(buf) != "\x00\x00\x00\x08\x04\xd2\x16\x2f" {
+			return // invalid length of startup packet
+		}
+		io.WriteString(conn, "N")
+		idle(conn)
+	})
+	r := probe(t, addr)
+	if r == nil || r.Protocol != PostgreSQL {
+		t.Errorf("expected postgresql, got %+v", r)
+	}
+}
+
+func TestProbe_Unidentified(t *testing.T) {
+	start := time.Now()
+	if r := probe(t, serve(t, idle)); r != nil {
+		t.Errorf("expected no result for a silent server, got %+v", r)
+	}
+	if elapsed := time.Since(start); elapsed > 10*testTimeout {
+		t.Errorf("probing a silent server took %v", elapsed)
+	}
+
+	// A server that echoes is not mistaken for anything.
+	if r := probe(t, serve(t, func(conn net.Conn) { io.Copy(conn, conn) })); r != nil {
+		t.Errorf("expected no result for an echo server, got %+v", r)
+	}
+}
+
+func TestProbe_Closed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	if _, err := Probe(context.Background(), addr, testTimeout); err == nil {
+		t.Error("expected an error for a closed port")
+	}
+}
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		e    port.PortEntry
+		want string
+	}{
+		{"wildcard", port.PortEntry{Port: 80, Protocol: port.TCP, State: port.StateListen, LocalAddr: "*", Family: port.IPv4}, "127.0.0.1:80"},
+		{"dual stack", port.PortEntry{Port: 80, Protocol: port.TCP, State: port.StateListen, LocalAddr: "*", Family: port.DualStack}, "127.0.0.1:80"},
+		{"IPv6 wildcard", port.PortEntry{Port: 80, Protocol: port.TCP, State: port.StateListen, LocalAddr: "*", Family: port.IPv6}, "[::1]:80"},
+		{"loopback", port.PortEntry{Port: 5432, Protocol: port.TCP, State: port.StateListen, LocalAddr: "127.0.0.1"}, "127.0.0.1:5432"},
+		{"link-local", port.PortEntry{Port: 22, Protocol: port.TCP, State: port.StateListen, LocalAddr: "fe80::1", Zone: "eth0"}, "[fe80::1%eth0]:22"},
+		{"udp", port.PortEntry{Port: 53, Protocol: port.UDP, State: port.StateListen, LocalAddr: "*"}, ""},
+		{"connection", port.PortEntry{Port: 80, Protocol: port.TCP, State: port.StateEstablished, LocalAddr: "127.0.0.1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Target(tt.e)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	sshAddr := serve(t, func(conn net.Conn) {
+		io.WriteString(conn, "SSH-2.0-dropbear\r\n")
+		idle(conn)
+	})
+	_, portStr, _ := net.SplitHostPort(sshAddr)
+	sshPort, _ := strconv.Atoi(portStr)
+
+	entries := []port.PortEntry{
+		{Port: sshPort, Protocol: port.TCP, State: port.StateListen, LocalAddr: "127.0.0.1"},
+		{Port: 53, Protocol: port.UDP, State: port.StateListen, LocalAddr: "*"},
+		{Port: sshPort, Protocol: port.TCP, State: port.StateListen, LocalAddr: "*", Family: port.IPv4},
+	}
+	results := Entries(context.Background(), entries, testTimeout)
+	if len(results) != len(entries) {
+		t.Fatalf("expected %d results, got %d", len(entries), len(results))
+	}
+	for _, i := range []int{0, 2} {
+		if results[i] == nil || results[i].String() != "ssh (dropbear)" {
+			t.Errorf("entry %d: got %v", i, results[i])
+		}
+	}
+	if results[1] != nil {
+		t.Errorf("expected UDP not to be probed, got %+v", results[1])
+	}
+}
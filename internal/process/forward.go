@@ -0,0 +1,341 @@
+package process
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Forward describes a local port that a forwarding process relays
+// elsewhere, such as an `ssh -L` tunnel.
+type Forward struct {
+	Tool       string // "ssh", "kubectl" or "socat"
+	BindAddr   string // local bind address, if given
+	ListenPort int    // local port, 0 if chosen at random
+	Target     string // where the traffic goes, e.g. "db:5432"
+	Via        string // ssh host or Kubernetes namespace, if any
+}
+
+// String returns e.g. "db:5432 (ssh via bastion)".
+func (f Forward) String() string {
+	if f.Via == "" {
+		return fmt.Sprintf("%s (%s)", f.Target, f.Tool)
+	}
+	return fmt.Sprintf("%s (%s via %s)", f.Target, f.Tool, f.Via)
+}
+
+// IsForwarder reports whether a process name belongs to a tool whose
+// listeners are usually port forwards.
+func IsForwarder(name string) bool {
+	switch name {
+	case "ssh", "kubectl", "socat":
+		return true
+	}
+	return false
+}
+
+// ForwardFor returns the forward listening on port, or nil. A forward
+// whose local port was chosen at random matches any port when it is the
+// process's only one.
+func ForwardFor(forwards []Forward, port int) *Forward {
+	for i, f := range forwards {
+		if f.ListenPort == port {
+			return &forwards[i]
+		}
+	}
+	if len(forwards) == 1 && forwards[0].ListenPort == 0 {
+		return &forwards[0]
+	}
+	return nil
+}
+
+// ParseForwards parses the command line of an ssh, kubectl port-forward or
+// socat process and returns the local ports it forwards. Other commands
+// return nil. The command line is split on spaces as printed by ps, so
+// quoted arguments containing spaces are not supported.
+func ParseForwards(command string) []Forward {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil
+	}
+	switch path.Base(args[0]) {
+	case "ssh":
+		return parseSSHForwards(args[1:])
+	case "kubectl":
+		return parseKubectlForwards(args[1:])
+	case "socat":
+		return parseSocatForward(args[1:])
+	}
+	return nil
+}
+
+// sshArgFlags lists the ssh options that take an argument.
+const sshArgFlags = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// parseSSHForwards handles -L local forwards and -D dynamic (SOCKS)
+// forwards. Remote forwards (-R) listen on the far side and are ignored.
+func parseSSHForwards(args []string) []Forward {
+	var forwards []Forward
+	host := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			if host == "" {
+				host = arg
+			}
+			continue
+		}
+
+		// Flags may be grouped ("-NfL") and the last may carry its
+		// argument inline ("-L5432:db:5432").
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			if !strings.ContainsRune(sshArgFlags, rune(flag)) {
+				continue
+			}
+			value := arg[j+1:]
+			if value == "" && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			switch flag {
+			case 'L':
+				if f, ok := parseSSHLocalForward(value); ok {
+					forwards = append(forwards, f)
+				}
+			case 'D':
+				bind, port := splitBindPort(value)
+				if port > 0 {
+					forwards = append(forwards, Forward{BindAddr: bind, ListenPort: port, Target: "SOCKS proxy"})
+				}
+			}
+			break
+		}
+	}
+
+	// ssh accepts user@host and ssh://user@host:port destinations.
+	host = strings.TrimPrefix(host, "ssh://")
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		host = host[at+1:]
+	}
+	for i := range forwards {
+		forwards[i].Tool = "ssh"
+		forwards[i].Via = host
+	}
+	return forwards
+}
+
+// parseSSHLocalForward parses [bind_address:]port:host:hostport and
+// [bind_address:]port:remote_socket.
+func parseSSHLocalForward(spec string) (Forward, bool) {
+	parts := splitHostPorts(spec)
+	var f Forward
+	n := len(parts)
+	switch {
+	case n >= 2 && strings.HasPrefix(parts[n-1], "/"):
+		f.Target, parts = parts[n-1], parts[:n-1]
+	case n >= 3:
+		f.Target, parts = joinHostPort(parts[n-2], parts[n-1]), parts[:n-2]
+	default:
+		return Forward{}, false
+	}
+
+	switch len(parts) {
+	case 1:
+	case 2:
+		f.BindAddr, parts = parts[0], parts[1:]
+	default:
+		return Forward{}, false
+	}
+	port, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Forward{}, false
+	}
+	f.ListenPort = port
+	return f, true
+}
+
+// kubectlArgFlags lists the kubectl flags that take a separate argument.
+var kubectlArgFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--kubeconfig": true,
+	"--cluster": true, "--user": true, "-s": true, "--server": true,
+	"--address": true, "--pod-running-timeout": true,
+}
+
+// parseKubectlForwards handles
+// `kubectl port-forward [-n ns] TYPE/NAME [LOCAL_PORT:]REMOTE_PORT...`.
+func parseKubectlForwards(args []string) []Forward {
+	var positional []string
+	namespace := ""
+	address := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		if !hasValue && kubectlArgFlags[name] && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "-n", "--namespace":
+			namespace = value
+		case "--address":
+			address = value
+		}
+	}
+
+	if len(positional) < 3 || positional[0] != "port-forward" {
+		return nil
+	}
+
+	resource := positional[1]
+	var forwards []Forward
+	for _, spec := range positional[2:] {
+		local, remote, ok := strings.Cut(spec, ":")
+		if !ok {
+			remote = local
+		}
+		port := 0
+		if local != "" {
+			p, err := strconv.Atoi(local)
+			if err != nil {
+				continue
+			}
+			port = p
+		}
+		via := "namespace default"
+		if namespace != "" {
+			via = "namespace " + namespace
+		}
+		forwards = append(forwards, Forward{
+			Tool:       "kubectl",
+			BindAddr:   address,
+			ListenPort: port,
+			Target:     resource + ":" + remote,
+			Via:        via,
+		})
+	}
+	return forwards
+}
+
+// parseSocatForward handles `socat TCP-LISTEN:port,opts ADDRESS`, with
+// the listening address on either side.
+func parseSocatForward(args []string) []Forward {
+	var addrs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-lf" || arg == "-lp" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		addrs = append(addrs, arg)
+	}
+	if len(addrs) != 2 {
+		return nil
+	}
+
+	for i, addr := range addrs {
+		typ, rest, ok := strings.Cut(addr, ":")
+		if !ok || !isSocatListen(typ) {
+			continue
+		}
+		portSpec, _, _ := strings.Cut(rest, ",")
+		port, err := strconv.Atoi(portSpec)
+		if err != nil {
+			return nil
+		}
+		return []Forward{{
+			Tool:       "socat",
+			ListenPort: port,
+			Target:     socatTarget(addrs[1-i]),
+		}}
+	}
+	return nil
+}
+
+// isSocatListen reports whether a socat address type listens on a port,
+// e.g. TCP-LISTEN, TCP4-L or UDP6-LISTEN.
+func isSocatListen(typ string) bool {
+	typ = strings.ToUpper(typ)
+	proto, mode, ok := strings.Cut(typ, "-")
+	if !ok || (mode != "LISTEN" && mode != "L") {
+		return false
+	}
+	switch proto {
+	case "TCP", "TCP4", "TCP6", "UDP", "UDP4", "UDP6", "SCTP", "OPENSSL":
+		return true
+	}
+	return false
+}
+
+// socatTarget turns a socat address such as "TCP:backend:80,retry=3"
+// into "backend:80".
+func socatTarget(addr string) string {
+	addr, _, _ = strings.Cut(addr, ",")
+	typ, rest, ok := strings.Cut(addr, ":")
+	if !ok {
+		return addr
+	}
+	switch strings.ToUpper(typ) {
+	case "TCP", "TCP4", "TCP6", "UDP", "UDP4", "UDP6", "OPENSSL", "SCTP",
+		"UNIX", "UNIX-CONNECT", "UNIX-CLIENT":
+		return rest
+	}
+	return addr
+}
+
+// splitHostPorts splits a colon-separated forward spec, keeping bracketed
+// IPv6 addresses such as "[::1]" in one piece without the brackets.
+func splitHostPorts(spec string) []string {
+	var parts []string
+	for spec != "" {
+		if strings.HasPrefix(spec, "[") {
+			end := strings.Index(spec, "]")
+			if end < 0 {
+				return nil
+			}
+			parts = append(parts, spec[1:end])
+			spec = strings.TrimPrefix(spec[end+1:], ":")
+			continue
+		}
+		part, rest, found := strings.Cut(spec, ":")
+		parts = append(parts, part)
+		spec = rest
+		if !found {
+			break
+		}
+	}
+	return parts
+}
+
+// splitBindPort parses "[bind_address:]port".
+func splitBindPort(spec string) (string, int) {
+	parts := splitHostPorts(spec)
+	bind := ""
+	if len(parts) == 2 {
+		bind, parts = parts[0], parts[1:]
+	}
+	if len(parts) != 1 {
+		return "", 0
+	}
+	port, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", 0
+	}
+	return bind, port
+}
+
+// joinHostPort joins a host and port, bracketing IPv6 addresses.
+func joinHostPort(host, port string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]:" + port
+	}
+	return host + ":" + port
+}
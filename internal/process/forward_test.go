@@ -0,0 +1,126 @@
+package process
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+func TestParseForwards(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []Forward
+	}{
+		{
+			name:    "ssh local forward",
+			command: "ssh -N -L 5432:db.internal:5432 deploy@bastion",
+			want:    []Forward{{Tool: "ssh", ListenPort: 5432, Target: "db.internal:5432", Via: "bastion"}},
+		},
+		{
+			name:    "ssh grouped flags and inline value",
+			command: "/usr/bin/ssh -fNL127.0.0.1:8080:localhost:80 -D 1080 -p 2222 jump",
+			want: []Forward{
+				{Tool: "ssh", BindAddr: "127.0.0.1", ListenPort: 8080, Target: "localhost:80", Via: "jump"},
+				{Tool: "ssh", ListenPort: 1080, Target: "SOCKS proxy", Via: "jump"},
+			},
+		},
+		{
+			name:    "ssh IPv6 and socket targets",
+			command: "ssh -L [::1]:6379:[fd00::5]:6379 -L 2375:/var/run/docker.sock -R 9000:localhost:9000 ssh://me@host:22",
+			want: []Forward{
+				{Tool: "ssh", BindAddr: "::1", ListenPort: 6379, Target: "[fd00::5]:6379", Via: "host:22"},
+				{Tool: "ssh", ListenPort: 2375, Target: "/var/run/docker.sock", Via: "host:22"},
+			},
+		},
+		{
+			name:    "ssh without forwards",
+			command: "ssh -i ~/.ssh/id_ed25519 server",
+		},
+		{
+			name:    "kubectl service",
+			command: "kubectl port-forward -n prod svc/api 8080:80 9090",
+			want: []Forward{
+				{Tool: "kubectl", ListenPort: 8080, Target: "svc/api:80", Via: "namespace prod"},
+				{Tool: "kubectl", ListenPort: 9090, Target: "svc/api:9090", Via: "namespace prod"},
+			},
+		},
+		{
+			name:    "kubectl global flags and random port",
+			command: "kubectl --context staging --namespace=web port-forward --address 0.0.0.0 pod/app-7d9 :3000",
+			want:    []Forward{{Tool: "kubectl", BindAddr: "0.0.0.0", Target: "pod/app-7d9:3000", Via: "namespace web"}},
+		},
+		{
+			name:    "kubectl other subcommand",
+			command: "kubectl get pods -w",
+		},
+		{
+			name:    "socat",
+			command: "socat -d -d TCP-LISTEN:8443,fork,reuseaddr TCP:backend:443",
+			want:    []Forward{{Tool: "socat", ListenPort: 8443, Target: "backend:443"}},
+		},
+		{
+			name:    "socat to unix socket",
+			command: "socat -lf /tmp/socat.log UNIX-CONNECT:/run/app.sock tcp4-l:9000,fork",
+			want:    []Forward{{Tool: "socat", ListenPort: 9000, Target: "/run/app.sock"}},
+		},
+		{
+			name:    "other command",
+			command: "nginx: master process /usr/sbin/nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseForwards(tt.command)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d forwards %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("[%d] got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestForwardFor(t *testing.T) {
+	forwards := ParseForwards("ssh -L 5432:db:5432 -L 6379:cache:6379 bastion")
+
+	f := ForwardFor(forwards, 6379)
+	if f == nil || f.Target != "cache:6379" {
+		t.Fatalf("got %v, want cache:6379", f)
+	}
+	if got := f.String(); got != "cache:6379 (ssh via bastion)" {
+		t.Errorf("String: got %q", got)
+	}
+	if ForwardFor(forwards, 8080) != nil {
+		t.Error("expected no forward on 8080")
+	}
+
+	random := ParseForwards("kubectl port-forward svc/api :80")
+	if f := ForwardFor(random, 41234); f == nil || f.Target != "svc/api:80" {
+		t.Errorf("random port: got %v", f)
+	}
+}
+
+func TestGetInfo_Forwards(t *testing.T) {
+	runner := &port.MultiMockCmdRunner{
+		Responses: map[string]port.MockResponse{
+			"ps -p 4242 -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=": {Output: []byte(
+				" 4242  1 alice  0.0  6120 Thu Feb 13 10:30:00 2026 ssh -N -L 5432:db:5432 bastion\n")},
+			"ps -p 4242 -o comm=": {Output: []byte("ssh\n")},
+		},
+	}
+
+	info, err := NewInfoFetcher(runner).GetInfo(context.Background(), 4242)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := ForwardFor(info.Forwards, 5432)
+	if f == nil || f.Target != "db:5432" || f.Via != "bastion" {
+		t.Errorf("got %v, want db:5432 via bastion", f)
+	}
+}
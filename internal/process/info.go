@@ -21,7 +21,8 @@ type ProcessInfo struct {
 	CPUPercent float64
 	MemRSS     int64 // in bytes
 	State      string
-	Children   []int // child PIDs
+	Children   []int     // child PIDs
+	Forwards   []Forward // ports relayed by ssh, kubectl or socat
 }
 
 // InfoFetcher retrieves detailed process information.
@@ -52,6 +53,8 @@ func (f *InfoFetcher) GetInfo(ctx context.Context, pid int) (*ProcessInfo, error
 		return nil, fmt.Errorf("failed to parse process info: %w", err)
 	}
 
+	info.Forwards = ParseForwards(info.Command)
+
 	// Get process name via shorter ps call.
 	nameOut, err := f.runner.Run(ctx, "ps", "-p", strconv.Itoa(pid), "-o", "comm=")
 	if err == nil {
@@ -69,9 +72,37 @@ func (f *InfoFetcher) GetInfo(ctx context.Context, pid int) (*ProcessInfo, error
 		info.Children = parseChildPIDs(string(childOut))
 	}
 
+	// The name and children are optional, but not when they are missing
+	// because the caller gave up.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return info, nil
 }
 
+// SnapshotInfo returns the information about pid recorded in a snapshot.
+// Unlike GetInfo it runs no commands, so it describes the process as it
+// was when the snapshot's sockets were collected.
+func SnapshotInfo(snap *port.Snapshot, pid int) (*ProcessInfo, error) {
+	p, ok := snap.Process(pid)
+	if !ok {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+	return &ProcessInfo{
+		PID:        p.PID,
+		PPID:       p.PPID,
+		Name:       p.Name,
+		Command:    p.Command,
+		User:       p.User,
+		StartTime:  p.StartTime,
+		CPUPercent: p.CPUPercent,
+		MemRSS:     p.MemRSS,
+		Children:   snap.Children(pid),
+		Forwards:   ParseForwards(p.Command),
+	}, nil
+}
+
 // parsePsOutput parses the output of ps -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=
 func parsePsOutput(line string) (*ProcessInfo, error) {
 	// Fields are space-separated, but lstart and command can contain spaces.
@@ -19,35 +19,58 @@ var protectedPIDs = map[int]bool{
 
 // Manager provides process lifecycle management.
 type Manager interface {
-	Kill(pid int, signal syscall.Signal) error
+	Kill(ctx context.Context, pid int, signal syscall.Signal) error
 	Info(ctx context.Context, pid int) (*ProcessInfo, error)
-	IsRunning(pid int) bool
+	IsRunning(ctx context.Context, pid int) bool
 }
 
-// RealManager implements Manager using real system calls.
+// RealManager implements Manager using real system calls, or with kill(1)
+// through the runner when the runner executes commands on a remote host.
 type RealManager struct {
 	runner  port.CmdRunner
 	fetcher *InfoFetcher
+	host    string // remote host, or "" to signal local processes
 }
 
-// NewRealManager creates a new process manager.
+// NewRealManager creates a new process manager. When runner is an
+// SSHCmdRunner, the manager signals processes on the remote host.
 func NewRealManager(runner port.CmdRunner) *RealManager {
 	return &RealManager{
 		runner:  runner,
 		fetcher: NewInfoFetcher(runner),
+		host:    port.RemoteHost(runner),
 	}
 }
 
+// signalNames maps the signals whport sends to the names kill(1) takes,
+// since signal numbers differ between systems.
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGTERM: "TERM",
+	syscall.SIGKILL: "KILL",
+	syscall.SIGINT:  "INT",
+	syscall.SIGHUP:  "HUP",
+	syscall.SIGUSR1: "USR1",
+	syscall.SIGUSR2: "USR2",
+}
+
 // Kill sends a signal to a process. It refuses to kill protected PIDs.
-func (m *RealManager) Kill(pid int, signal syscall.Signal) error {
+func (m *RealManager) Kill(ctx context.Context, pid int, signal syscall.Signal) error {
 	if protectedPIDs[pid] {
 		return fmt.Errorf("refusing to kill protected PID %d", pid)
 	}
 
-	if !m.IsRunning(pid) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !m.IsRunning(ctx, pid) {
 		return fmt.Errorf("process %d is not running", pid)
 	}
 
+	if m.host != "" {
+		return m.remoteKill(ctx, pid, signal)
+	}
+
 	if err := syscall.Kill(pid, signal); err != nil {
 		return fmt.Errorf("failed to send signal %d to PID %d: %w", signal, pid, err)
 	}
@@ -55,28 +78,45 @@ func (m *RealManager) Kill(pid int, signal syscall.Signal) error {
 	return nil
 }
 
+// remoteKill sends a signal with kill(1) on the remote host.
+func (m *RealManager) remoteKill(ctx context.Context, pid int, signal syscall.Signal) error {
+	name, ok := signalNames[signal]
+	if !ok {
+		return fmt.Errorf("signal %d cannot be sent to a remote host", signal)
+	}
+	if _, err := m.runner.Run(ctx, "kill", "-s", name, strconv.Itoa(pid)); err != nil {
+		return fmt.Errorf("failed to send SIG%s to PID %d on %s: %w", name, pid, m.host, err)
+	}
+	return nil
+}
+
 // GracefulKill sends SIGTERM, waits up to 3 seconds, then returns whether
-// the process exited. The caller can then decide to SIGKILL.
-func (m *RealManager) GracefulKill(pid int) (exited bool, err error) {
-	if err := m.Kill(pid, syscall.SIGTERM); err != nil {
+// the process exited. The caller can then decide to SIGKILL. Waiting stops
+// early when ctx is done.
+func (m *RealManager) GracefulKill(ctx context.Context, pid int) (exited bool, err error) {
+	if err := m.Kill(ctx, pid, syscall.SIGTERM); err != nil {
 		return false, err
 	}
 
 	// Poll for up to 3 seconds.
 	deadline := time.Now().Add(3 * time.Second)
 	for time.Now().Before(deadline) {
-		if !m.IsRunning(pid) {
+		if !m.IsRunning(ctx, pid) {
 			return true, nil
 		}
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 
-	return !m.IsRunning(pid), nil
+	return !m.IsRunning(ctx, pid), nil
 }
 
 // ForceKill sends SIGKILL to a process.
-func (m *RealManager) ForceKill(pid int) error {
-	return m.Kill(pid, syscall.SIGKILL)
+func (m *RealManager) ForceKill(ctx context.Context, pid int) error {
+	return m.Kill(ctx, pid, syscall.SIGKILL)
 }
 
 // Info retrieves detailed process information.
@@ -85,8 +125,12 @@ func (m *RealManager) Info(ctx context.Context, pid int) (*ProcessInfo, error) {
 }
 
 // IsRunning checks if a process with the given PID exists.
-func (m *RealManager) IsRunning(pid int) bool {
+func (m *RealManager) IsRunning(ctx context.Context, pid int) bool {
 	// On Unix, sending signal 0 checks if the process exists.
+	if m.host != "" {
+		_, err := m.runner.Run(ctx, "kill", "-0", strconv.Itoa(pid))
+		return err == nil
+	}
 	return syscall.Kill(pid, 0) == nil
 }
 
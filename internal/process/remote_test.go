@@ -0,0 +1,103 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+const sshPrefix = "ssh -o BatchMode=yes -T -- dev@box "
+
+// exitingRunner records commands and reports the process as gone once it
+// has been sent SIGTERM.
+type exitingRunner struct {
+	port.RecordingCmdRunner
+	terminated bool
+}
+
+func (r *exitingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := r.RecordingCmdRunner.Run(ctx, name, args...)
+	cmd := strings.Join(args, " ")
+	switch {
+	case strings.HasSuffix(cmd, " kill -s TERM 5678"):
+		r.terminated = true
+	case strings.HasSuffix(cmd, " kill -0 5678") && r.terminated:
+		return nil, errors.New("exit status 1")
+	}
+	return out, err
+}
+
+func TestRealManager_RemoteKill(t *testing.T) {
+	rec := &port.RecordingCmdRunner{}
+	m := NewRealManager(port.NewSSHCmdRunner("dev@box", rec))
+
+	if err := m.Kill(context.Background(), 5678, syscall.SIGHUP); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.ForceKill(context.Background(), 5678); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		sshPrefix + "kill -0 5678",
+		sshPrefix + "kill -s HUP 5678",
+		sshPrefix + "kill -0 5678",
+		sshPrefix + "kill -s KILL 5678",
+	}
+	if strings.Join(rec.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\ngot  %q\nwant %q", rec.Commands, want)
+	}
+}
+
+func TestRealManager_RemoteGracefulKill(t *testing.T) {
+	r := &exitingRunner{}
+	m := NewRealManager(port.NewSSHCmdRunner("dev@box", r))
+
+	exited, err := m.GracefulKill(context.Background(), 5678)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !exited {
+		t.Error("expected the process to have exited")
+	}
+	want := []string{
+		sshPrefix + "kill -0 5678",
+		sshPrefix + "kill -s TERM 5678",
+		sshPrefix + "kill -0 5678",
+	}
+	if strings.Join(r.Commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\ngot  %q\nwant %q", r.Commands, want)
+	}
+}
+
+func TestRealManager_RemoteKillErrors(t *testing.T) {
+	rec := &port.RecordingCmdRunner{MultiMockCmdRunner: port.MultiMockCmdRunner{Responses: map[string]port.MockResponse{
+		sshPrefix + "kill -0 42": {Err: errors.New("exit status 1")},
+	}}}
+	m := NewRealManager(port.NewSSHCmdRunner("dev@box", rec))
+
+	if err := m.Kill(context.Background(), 42, syscall.SIGTERM); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("expected a not running error, got %v", err)
+	}
+	if err := m.Kill(context.Background(), 1, syscall.SIGTERM); err == nil || !strings.Contains(err.Error(), "protected") {
+		t.Errorf("expected PID 1 to be protected, got %v", err)
+	}
+	if err := m.Kill(context.Background(), 5678, syscall.SIGWINCH); err == nil || !strings.Contains(err.Error(), "remote host") {
+		t.Errorf("expected an unsupported signal error, got %v", err)
+	}
+}
+
+func TestRealManager_RemoteVerifyProcess(t *testing.T) {
+	rec := &port.RecordingCmdRunner{MultiMockCmdRunner: port.MultiMockCmdRunner{Responses: map[string]port.MockResponse{
+		sshPrefix + "ps -p 5678 -o comm=": {Output: []byte("/usr/bin/node\n")},
+	}}}
+	m := NewRealManager(port.NewSSHCmdRunner("dev@box", rec))
+
+	if !m.VerifyProcess(context.Background(), 5678, "node") {
+		t.Error("expected the remote process to verify")
+	}
+}
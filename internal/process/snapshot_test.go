@@ -0,0 +1,116 @@
+package process
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+// countingRunner counts the subprocesses a scan would start.
+type countingRunner struct {
+	port.MultiMockCmdRunner
+	calls atomic.Int64
+}
+
+func (r *countingRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	r.calls.Add(1)
+	return r.MultiMockCmdRunner.Run(ctx, name, args...)
+}
+
+func (r *countingRunner) Stream(ctx context.Context, fn func(io.Reader) error, name string, args ...string) error {
+	r.calls.Add(1)
+	return r.MultiMockCmdRunner.Stream(ctx, fn, name, args...)
+}
+
+// newInfoRunner returns a runner answering the commands `whport info 3000`
+// runs on the lsof backend, with and without snapshots.
+func newInfoRunner() *countingRunner {
+	return &countingRunner{MultiMockCmdRunner: port.MultiMockCmdRunner{
+		Responses: map[string]port.MockResponse{
+			"lsof +c 0 -F pcuLfatPnT -i:3000 -P -n": {Output: []byte(
+				"p5678\ncnode\nu501\nf8\nau\ntIPv6\nPTCP\nn*:3000\nTST=LISTEN\n")},
+			"ps -A -ww -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=": {Output: []byte(
+				"  812     1 alice  0.0  2048 Thu Feb 13 10:29:58 2026 -zsh\n" +
+					" 5678   812 alice  1.5 81920 Thu Feb 13 10:30:00 2026 node server.js\n" +
+					" 5690  5678 alice  0.3 20480 Thu Feb 13 10:30:01 2026 node worker.js\n")},
+			"ps -p 5678 -o pid=,ppid=,user=,%cpu=,rss=,lstart=,command=": {Output: []byte(
+				" 5678   812 alice  1.5 81920 Thu Feb 13 10:30:00 2026 node server.js\n")},
+			"ps -p 5678 -o comm=": {Output: []byte("node\n")},
+			"pgrep -P 5678":       {Output: []byte("5690\n")},
+		},
+	}}
+}
+
+func TestSnapshotInfo(t *testing.T) {
+	runner := newInfoRunner()
+	snap, err := port.TakeSnapshot(context.Background(), port.NewLsofScanner(runner), "lsof", port.SnapshotOptions{Port: 3000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := SnapshotInfo(snap, 5678)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Name != "node" || info.Command != "node server.js" || info.User != "alice" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if info.PPID != 812 || info.MemRSS != 81920*1024 || info.CPUPercent != 1.5 {
+		t.Errorf("unexpected ppid/rss/cpu: %d/%d/%.1f", info.PPID, info.MemRSS, info.CPUPercent)
+	}
+	if len(info.Children) != 1 || info.Children[0] != 5690 {
+		t.Errorf("children: got %v, want [5690]", info.Children)
+	}
+	if got := runner.calls.Load(); got != 2 {
+		t.Errorf("expected 2 subprocesses, got %d", got)
+	}
+
+	if _, err := SnapshotInfo(snap, 9999); err == nil {
+		t.Error("expected error for a PID missing from the snapshot")
+	}
+}
+
+// BenchmarkInfo_Separate looks up the port and then asks ps and pgrep
+// about its process, as info did before snapshots.
+func BenchmarkInfo_Separate(b *testing.B) {
+	runner := newInfoRunner()
+	scanner := port.NewLsofScanner(runner)
+	fetcher := NewInfoFetcher(runner)
+	ctx := context.Background()
+
+	for b.Loop() {
+		entries, err := scanner.FindByPort(ctx, 3000)
+		if err != nil || len(entries) == 0 {
+			b.Fatalf("scan failed: %v", err)
+		}
+		if _, err := fetcher.GetInfo(ctx, entries[0].PID); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(runner.calls.Load())/float64(b.N), "subprocs/op")
+}
+
+// BenchmarkInfo_Snapshot answers the same question from one snapshot.
+func BenchmarkInfo_Snapshot(b *testing.B) {
+	runner := newInfoRunner()
+	scanner := port.NewLsofScanner(runner)
+	ctx := context.Background()
+
+	for b.Loop() {
+		snap, err := port.TakeSnapshot(ctx, scanner, "lsof", port.SnapshotOptions{Port: 3000})
+		if err != nil || len(snap.Entries) == 0 {
+			b.Fatalf("scan failed: %v", err)
+		}
+		info, err := SnapshotInfo(snap, snap.Entries[0].PID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(info.Children) != 1 {
+			b.Fatalf("expected one child, got %v", info.Children)
+		}
+	}
+	b.ReportMetric(float64(runner.calls.Load())/float64(b.N), "subprocs/op")
+}
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+func TestGetInfo_Timeout(t *testing.T) {
+	f := NewInfoFetcher(&port.BlockingCmdRunner{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := f.GetInfo(ctx, 1234); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestVerifyProcess_Timeout(t *testing.T) {
+	m := NewRealManager(&port.BlockingCmdRunner{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if m.VerifyProcess(ctx, 1234, "node") {
+		t.Error("expected a process that cannot be checked not to verify")
+	}
+}
+
+func TestKill_Cancelled(t *testing.T) {
+	m := NewRealManager(&port.BlockingCmdRunner{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := m.Kill(ctx, 999999, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+}
+
+// GracefulKill stops waiting for a process that ignores SIGTERM once its
+// context is done.
+func TestGracefulKill_Timeout(t *testing.T) {
+	cmd := exec.Command("sh", "-c", `trap "" TERM; sleep 5`)
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sh: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	// Give the shell time to install its trap.
+	time.Sleep(100 * time.Millisecond)
+
+	m := NewRealManager(&port.BlockingCmdRunner{})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	exited, err := m.GracefulKill(ctx, cmd.Process.Pid)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if exited {
+		t.Error("expected the process to still be running")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GracefulKill took %v with a 100ms timeout", elapsed)
+	}
+}
@@ -0,0 +1,94 @@
+package procwatch
+
+import (
+	"encoding/binary"
+)
+
+// Proc connector event types from linux/cn_proc.h.
+const (
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+)
+
+// Sizes of struct cn_msg and of the proc_event header (what, cpu and
+// timestamp_ns) that precede the event data.
+const (
+	cnMsgLen        = 20
+	procEventHdrLen = 16
+)
+
+// procEvent is the part of a proc connector event that is used. For fork
+// events pid and tgid describe the child.
+type procEvent struct {
+	what       uint32
+	pid        int
+	tgid       int
+	parentTgid int // fork only
+}
+
+// parseProcEvent parses the payload of a proc connector netlink message:
+// a struct cn_msg followed by a struct proc_event.
+func parseProcEvent(data []byte) (procEvent, bool) {
+	if len(data) < cnMsgLen+procEventHdrLen {
+		return procEvent{}, false
+	}
+	ev := data[cnMsgLen:]
+	body := ev[procEventHdrLen:]
+	u32 := func(i int) int { return int(binary.NativeEndian.Uint32(body[4*i:])) }
+
+	e := procEvent{what: binary.NativeEndian.Uint32(ev)}
+	switch e.what {
+	case procEventFork:
+		if len(body) < 16 {
+			return procEvent{}, false
+		}
+		e.parentTgid = u32(1)
+		e.pid, e.tgid = u32(2), u32(3)
+	case procEventExec, procEventExit:
+		if len(body) < 8 {
+			return procEvent{}, false
+		}
+		e.pid, e.tgid = u32(0), u32(1)
+	default:
+		return procEvent{}, false
+	}
+	return e, true
+}
+
+// eventFilter decides which events can change the socket table. Thread
+// creation and exit are ignored, as are the processes whport starts
+// itself to scan, such as lsof and ps, and their descendants; otherwise
+// every scan would trigger the next one.
+type eventFilter struct {
+	self int
+	own  map[int]bool // descendants of self
+}
+
+func newEventFilter(self int) *eventFilter {
+	return &eventFilter{self: self, own: make(map[int]bool)}
+}
+
+// relevant reports whether e should cause a rescan.
+func (f *eventFilter) relevant(e procEvent) bool {
+	if e.pid != e.tgid {
+		return false // a thread
+	}
+	switch e.what {
+	case procEventFork:
+		if e.parentTgid == f.self || f.own[e.parentTgid] {
+			f.own[e.tgid] = true
+			return false
+		}
+		return true
+	case procEventExec:
+		return !f.own[e.tgid]
+	case procEventExit:
+		if f.own[e.tgid] {
+			delete(f.own, e.tgid)
+			return false
+		}
+		return true
+	}
+	return false
+}
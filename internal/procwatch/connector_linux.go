@@ -0,0 +1,156 @@
+//go:build linux
+
+package procwatch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Proc connector constants from linux/connector.h and linux/cn_proc.h.
+const (
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+	procEventNone     = 0
+)
+
+// ackTimeout bounds the wait for the kernel to confirm the subscription.
+// Kernels built without CONFIG_PROC_EVENTS accept the socket but never
+// answer.
+const ackTimeout = time.Second
+
+// connector is a subscription to the proc connector.
+type connector struct {
+	f  *os.File
+	ch chan struct{}
+}
+
+// openConnector subscribes to process events. It needs CAP_NET_ADMIN, so
+// it fails for unprivileged users and in most containers.
+func openConnector() (source, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	sa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	if err := syscall.Sendto(fd, listenRequest(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("sendto", err)
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+
+	// With a non-blocking descriptor, reads go through the runtime poller
+	// and can be interrupted by a deadline or by Close.
+	c := &connector{f: os.NewFile(uintptr(fd), "proc-connector"), ch: make(chan struct{}, 1)}
+	if err := c.waitAck(); err != nil {
+		c.f.Close()
+		return nil, err
+	}
+	go c.read()
+	return c, nil
+}
+
+// listenRequest builds the netlink message that subscribes to events.
+func listenRequest() []byte {
+	msg := make([]byte, syscall.NLMSG_HDRLEN+cnMsgLen+4)
+	ne := binary.NativeEndian
+	ne.PutUint32(msg[0:], uint32(len(msg)))
+	ne.PutUint16(msg[4:], syscall.NLMSG_DONE)
+	ne.PutUint32(msg[12:], uint32(os.Getpid()))
+
+	cn := msg[syscall.NLMSG_HDRLEN:]
+	ne.PutUint32(cn[0:], cnIdxProc)
+	ne.PutUint32(cn[4:], cnValProc)
+	ne.PutUint16(cn[16:], 4)
+	ne.PutUint32(cn[cnMsgLen:], procCnMcastListen)
+	return msg
+}
+
+// waitAck waits for the kernel's reply to the listen request, skipping
+// any events that arrive first.
+func (c *connector) waitAck() error {
+	if err := c.f.SetReadDeadline(time.Now().Add(ackTimeout)); err != nil {
+		return err
+	}
+	defer c.f.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := c.f.Read(buf)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to process events: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			data := m.Data
+			if len(data) < cnMsgLen+procEventHdrLen+4 {
+				continue
+			}
+			if binary.NativeEndian.Uint32(data[cnMsgLen:]) != procEventNone {
+				continue
+			}
+			if errno := binary.NativeEndian.Uint32(data[cnMsgLen+procEventHdrLen:]); errno != 0 {
+				return fmt.Errorf("failed to subscribe to process events: %w", syscall.Errno(errno))
+			}
+			return nil
+		}
+	}
+}
+
+// read forwards relevant events until the connector is closed or fails.
+func (c *connector) read() {
+	defer close(c.ch)
+
+	filter := newEventFilter(os.Getpid())
+	buf := make([]byte, 16*1024)
+	for {
+		n, err := c.f.Read(buf)
+		if err != nil {
+			if errors.Is(err, syscall.ENOBUFS) {
+				// Events were dropped; something changed.
+				c.signal()
+				continue
+			}
+			return
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if e, ok := parseProcEvent(m.Data); ok && filter.relevant(e) {
+				c.signal()
+			}
+		}
+	}
+}
+
+func (c *connector) signal() {
+	select {
+	case c.ch <- struct{}{}:
+	default:
+	}
+}
+
+func (c *connector) events() <-chan struct{} {
+	return c.ch
+}
+
+func (c *connector) close() error {
+	return c.f.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package procwatch
+
+import "errors"
+
+// openConnector always fails outside Linux, so triggers poll.
+func openConnector() (source, error) {
+	return nil, errors.New("process events are only supported on Linux")
+}
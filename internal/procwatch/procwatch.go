@@ -0,0 +1,190 @@
+// Package procwatch decides when watch and the TUI should rescan. On
+// Linux it listens for process events through the netlink proc connector
+// and rescans shortly after processes start or exit, with a slow safety
+// poll for changes that no event announces, such as a running process
+// opening a new listener. Elsewhere, or when the connector cannot be
+// used, it polls at a fixed interval.
+package procwatch
+
+import (
+	"sync"
+	"time"
+)
+
+// Default timings for event-driven refresh.
+const (
+	// DefaultSettle is how long to wait after an event before rescanning,
+	// so that a burst of events causes one scan.
+	DefaultSettle = 200 * time.Millisecond
+	// DefaultFollowUp is when to rescan again after an event-driven scan,
+	// for servers that take a moment to bind after they start.
+	DefaultFollowUp = 2 * time.Second
+	// DefaultMinGap limits event-driven scans on hosts that start
+	// processes continuously.
+	DefaultMinGap = time.Second
+	// DefaultSafetyPoll is how often to rescan when no events arrive.
+	DefaultSafetyPoll = 30 * time.Second
+)
+
+// Options configures a Trigger. Zero durations use the defaults.
+type Options struct {
+	Interval   time.Duration // polling interval without process events
+	Settle     time.Duration
+	FollowUp   time.Duration
+	MinGap     time.Duration
+	SafetyPoll time.Duration
+	Poll       bool // always poll, even if process events are available
+}
+
+// Trigger delivers a value on C whenever a rescan is due. Deliveries do
+// not queue: a rescan that is due while the previous one is still being
+// handled is merged into it.
+type Trigger struct {
+	C <-chan struct{}
+
+	c      chan struct{}
+	src    source
+	events bool
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// source reports that relevant process events happened. The channel is
+// closed when the source fails.
+type source interface {
+	events() <-chan struct{}
+	close() error
+}
+
+// New starts a Trigger. It uses the proc connector when it can be opened
+// and falls back to polling at opts.Interval otherwise.
+func New(opts Options) *Trigger {
+	var src source
+	if !opts.Poll {
+		src, _ = openConnector()
+	}
+	return newTrigger(opts, src)
+}
+
+func newTrigger(opts Options, src source) *Trigger {
+	opts = withDefaults(opts)
+	c := make(chan struct{}, 1)
+	t := &Trigger{
+		C:      c,
+		c:      c,
+		src:    src,
+		events: src != nil,
+		stop:   make(chan struct{}),
+	}
+	if src != nil {
+		go t.runEvents(opts)
+	} else {
+		go t.runPoll(opts.Interval)
+	}
+	return t
+}
+
+func withDefaults(opts Options) Options {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultSettle
+	}
+	if opts.FollowUp <= 0 {
+		opts.FollowUp = DefaultFollowUp
+	}
+	if opts.MinGap <= 0 {
+		opts.MinGap = DefaultMinGap
+	}
+	if opts.SafetyPoll <= 0 {
+		opts.SafetyPoll = DefaultSafetyPoll
+	}
+	return opts
+}
+
+// Events reports whether process events drive the trigger, as opposed to
+// interval polling.
+func (t *Trigger) Events() bool {
+	return t.events
+}
+
+// Stop stops the trigger and releases the proc connector. No more values
+// are sent on C after Stop returns.
+func (t *Trigger) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+		if t.src != nil {
+			t.src.close()
+		}
+	})
+}
+
+// fire delivers a rescan unless one is already pending.
+func (t *Trigger) fire() {
+	select {
+	case t.c <- struct{}{}:
+	default:
+	}
+}
+
+func (t *Trigger) runPoll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.fire()
+		}
+	}
+}
+
+// runEvents rescans after events, settling bursts and spacing scans at
+// least MinGap apart, and polls slowly in between. If the source fails,
+// it switches to interval polling.
+func (t *Trigger) runEvents(opts Options) {
+	safety := time.NewTimer(opts.SafetyPoll)
+	defer safety.Stop()
+
+	var (
+		events   = t.src.events()
+		settle   <-chan time.Time
+		followUp <-chan time.Time
+		last     time.Time
+	)
+	scan := func() {
+		t.fire()
+		last = time.Now()
+		safety.Reset(opts.SafetyPoll)
+	}
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case _, ok := <-events:
+			if !ok {
+				t.runPoll(opts.Interval)
+				return
+			}
+			if settle == nil {
+				wait := opts.Settle
+				if gap := opts.MinGap - time.Since(last); gap > wait {
+					wait = gap
+				}
+				settle = time.After(wait)
+			}
+		case <-settle:
+			settle = nil
+			scan()
+			followUp = time.After(opts.FollowUp)
+		case <-followUp:
+			followUp = nil
+			scan()
+		case <-safety.C:
+			scan()
+		}
+	}
+}
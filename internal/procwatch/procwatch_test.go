@@ -0,0 +1,161 @@
+package procwatch
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// fakeSource is a source driven by the test.
+type fakeSource struct {
+	ch     chan struct{}
+	closed bool
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{ch: make(chan struct{}, 1)}
+}
+
+func (s *fakeSource) events() <-chan struct{} { return s.ch }
+func (s *fakeSource) close() error            { s.closed = true; return nil }
+
+// procEventMsg builds a proc connector payload with the given event data.
+func procEventMsg(what uint32, data ...uint32) []byte {
+	msg := make([]byte, cnMsgLen+procEventHdrLen+4*len(data))
+	binary.NativeEndian.PutUint32(msg[cnMsgLen:], what)
+	for i, v := range data {
+		binary.NativeEndian.PutUint32(msg[cnMsgLen+procEventHdrLen+4*i:], v)
+	}
+	return msg
+}
+
+func TestParseProcEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want procEvent
+		ok   bool
+	}{
+		{"fork", procEventMsg(procEventFork, 10, 10, 42, 42), procEvent{what: procEventFork, pid: 42, tgid: 42, parentTgid: 10}, true},
+		{"exec", procEventMsg(procEventExec, 42, 42), procEvent{what: procEventExec, pid: 42, tgid: 42}, true},
+		{"exit", procEventMsg(procEventExit, 43, 42, 0, 17, 10, 10), procEvent{what: procEventExit, pid: 43, tgid: 42}, true},
+		{"uid change", procEventMsg(0x4, 42, 42, 0, 0), procEvent{}, false},
+		{"truncated", procEventMsg(procEventFork, 10, 10), procEvent{}, false},
+		{"short", make([]byte, 8), procEvent{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProcEvent(tt.data)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("got %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	f := newEventFilter(100)
+
+	steps := []struct {
+		name string
+		e    procEvent
+		want bool
+	}{
+		{"other process starts", procEvent{what: procEventFork, pid: 200, tgid: 200, parentTgid: 1}, true},
+		{"other process execs", procEvent{what: procEventExec, pid: 200, tgid: 200}, true},
+		{"thread is created", procEvent{what: procEventFork, pid: 201, tgid: 200, parentTgid: 200}, false},
+		{"we start lsof", procEvent{what: procEventFork, pid: 300, tgid: 300, parentTgid: 100}, false},
+		{"lsof execs", procEvent{what: procEventExec, pid: 300, tgid: 300}, false},
+		{"lsof forks a helper", procEvent{what: procEventFork, pid: 301, tgid: 301, parentTgid: 300}, false},
+		{"helper exits", procEvent{what: procEventExit, pid: 301, tgid: 301}, false},
+		{"lsof exits", procEvent{what: procEventExit, pid: 300, tgid: 300}, false},
+		{"PID is reused by another process", procEvent{what: procEventExec, pid: 300, tgid: 300}, true},
+		{"other process exits", procEvent{what: procEventExit, pid: 200, tgid: 200}, true},
+	}
+	for _, s := range steps {
+		if got := f.relevant(s.e); got != s.want {
+			t.Errorf("%s: got %v, want %v", s.name, got, s.want)
+		}
+	}
+	if len(f.own) != 0 {
+		t.Errorf("expected no tracked processes, got %v", f.own)
+	}
+}
+
+// expectFire waits for a delivery on t.C within d.
+func expectFire(t *testing.T, tr *Trigger, d time.Duration, what string) {
+	t.Helper()
+	select {
+	case <-tr.C:
+	case <-time.After(d):
+		t.Fatalf("expected a rescan %s", what)
+	}
+}
+
+// expectQuiet checks that nothing is delivered on t.C for d.
+func expectQuiet(t *testing.T, tr *Trigger, d time.Duration, what string) {
+	t.Helper()
+	select {
+	case <-tr.C:
+		t.Fatalf("unexpected rescan %s", what)
+	case <-time.After(d):
+	}
+}
+
+func TestTrigger_Events(t *testing.T) {
+	src := newFakeSource()
+	tr := newTrigger(Options{
+		Interval:   10 * time.Millisecond,
+		Settle:     20 * time.Millisecond,
+		FollowUp:   150 * time.Millisecond,
+		MinGap:     20 * time.Millisecond,
+		SafetyPoll: time.Hour,
+	}, src)
+	defer tr.Stop()
+
+	if !tr.Events() {
+		t.Fatal("expected an event-driven trigger")
+	}
+	expectQuiet(t, tr, 50*time.Millisecond, "without events")
+
+	// A burst of events causes one rescan, then a follow-up.
+	for range 3 {
+		src.ch <- struct{}{}
+		time.Sleep(2 * time.Millisecond)
+	}
+	expectFire(t, tr, time.Second, "after events")
+	expectQuiet(t, tr, 60*time.Millisecond, "before the follow-up")
+	expectFire(t, tr, time.Second, "as a follow-up")
+
+	tr.Stop()
+	if !src.closed {
+		t.Error("expected Stop to close the source")
+	}
+}
+
+func TestTrigger_SafetyPoll(t *testing.T) {
+	tr := newTrigger(Options{SafetyPoll: 20 * time.Millisecond}, newFakeSource())
+	defer tr.Stop()
+
+	expectFire(t, tr, time.Second, "from the safety poll")
+}
+
+func TestTrigger_SourceFailureFallsBackToPolling(t *testing.T) {
+	src := newFakeSource()
+	tr := newTrigger(Options{Interval: 20 * time.Millisecond, SafetyPoll: time.Hour}, src)
+	defer tr.Stop()
+
+	close(src.ch)
+	expectFire(t, tr, time.Second, "from polling")
+	expectFire(t, tr, time.Second, "from polling again")
+}
+
+func TestTrigger_Poll(t *testing.T) {
+	tr := New(Options{Interval: 20 * time.Millisecond, Poll: true})
+	defer tr.Stop()
+
+	if tr.Events() {
+		t.Fatal("expected a polling trigger")
+	}
+	expectFire(t, tr, time.Second, "from polling")
+}
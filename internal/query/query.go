@@ -0,0 +1,442 @@
+// Package query implements the filter expressions accepted by --where and
+// the TUI search, such as
+//
+//	user == me && port >= 3000 && state == LISTEN && bind != 127.0.0.1
+//
+// An expression compares entry fields with values using ==, !=, <, <=, >,
+// >= and ~ (contains) or !~, and combines comparisons with &&, || and !
+// and parentheses. String comparisons ignore case. Values are bare words
+// or quoted strings; the bare word me stands for the current user.
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+// Env holds what an expression needs beyond the entry itself.
+type Env struct {
+	User     string                // the user "me" stands for
+	Services *port.ServiceResolver // for the service field and service names as ports
+}
+
+// Error is a parse error at a column of the expression, counted from 1.
+type Error struct {
+	Col int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Col, e.Msg)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+	refs map[string]bool // fields the expression compares
+}
+
+// Parse parses an expression.
+func Parse(src string, env Env) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks, env: &env, refs: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return &Expr{src: src, root: root, refs: p.refs}, nil
+}
+
+// Match reports whether the entry satisfies the expression. A nil
+// expression matches every entry.
+func (x *Expr) Match(e port.PortEntry) bool {
+	if x == nil {
+		return true
+	}
+	return x.root.match(e)
+}
+
+// References reports whether the expression compares any of the named
+// fields, such as the connection fields that a scan of listeners lacks.
+func (x *Expr) References(names ...string) bool {
+	if x == nil {
+		return false
+	}
+	for _, name := range names {
+		if x.refs[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the expression as it was given.
+func (x *Expr) String() string {
+	return x.src
+}
+
+// IsExpression reports whether s looks like an expression rather than
+// plain search text, that is, whether it contains a comparison operator.
+func IsExpression(s string) bool {
+	return strings.ContainsAny(s, "=<>~")
+}
+
+// field is an entry field that expressions can refer to. Numeric fields
+// have num set, the others str. match, if set, replaces equality.
+type field struct {
+	num   func(e port.PortEntry) int
+	str   func(e port.PortEntry, env *Env) string
+	match func(e port.PortEntry, v string) bool
+}
+
+var fields = map[string]field{
+	"port":        {num: func(e port.PortEntry) int { return e.Port }},
+	"pid":         {num: func(e port.PortEntry) int { return e.PID }},
+	"ppid":        {num: func(e port.PortEntry) int { return e.PPID }},
+	"remote_port": {num: func(e port.PortEntry) int { return e.RemotePort }},
+	"netns":       {num: func(e port.PortEntry) int { return int(e.NetNS) }},
+
+	"proto":       {str: func(e port.PortEntry, _ *Env) string { return string(e.Protocol) }},
+	"process":     {str: func(e port.PortEntry, _ *Env) string { return e.Process }},
+	"user":        {str: func(e port.PortEntry, _ *Env) string { return e.User }},
+	"command":     {str: func(e port.PortEntry, _ *Env) string { return e.Command }},
+	"state":       {str: func(e port.PortEntry, _ *Env) string { return string(e.State) }},
+	"bind":        {str: func(e port.PortEntry, _ *Env) string { return e.Bind() }},
+	"family":      {str: func(e port.PortEntry, _ *Env) string { return string(e.Family) }},
+	"remote":      {str: func(e port.PortEntry, _ *Env) string { return e.Remote() }},
+	"remote_addr": {str: func(e port.PortEntry, _ *Env) string { return e.RemoteAddr }},
+	"path":        {str: func(e port.PortEntry, _ *Env) string { return e.Path }},
+	"service":     {str: func(e port.PortEntry, env *Env) string { return env.Services.Name(e.Port, e.Protocol) }},
+	"container": {
+		str:   func(e port.PortEntry, _ *Env) string { return e.Container.String() },
+		match: func(e port.PortEntry, v string) bool { return e.Container.Match(v) },
+	},
+}
+
+// fieldAliases are other names accepted for fields.
+var fieldAliases = map[string]string{
+	"protocol": "proto",
+	"name":     "process",
+	"cmd":      "command",
+}
+
+// fieldNames lists the fields for error messages.
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type node interface {
+	match(e port.PortEntry) bool
+}
+
+type andNode struct{ l, r node }
+type orNode struct{ l, r node }
+type notNode struct{ x node }
+
+func (n andNode) match(e port.PortEntry) bool { return n.l.match(e) && n.r.match(e) }
+func (n orNode) match(e port.PortEntry) bool  { return n.l.match(e) || n.r.match(e) }
+func (n notNode) match(e port.PortEntry) bool { return !n.x.match(e) }
+
+// cmpNode compares a field with a value parsed for it.
+type cmpNode struct {
+	f   field
+	op  string
+	num int
+	str string
+	env *Env
+}
+
+func (n cmpNode) match(e port.PortEntry) bool {
+	if n.f.num != nil {
+		v := n.f.num(e)
+		switch n.op {
+		case "==":
+			return v == n.num
+		case "!=":
+			return v != n.num
+		case "<":
+			return v < n.num
+		case "<=":
+			return v <= n.num
+		case ">":
+			return v > n.num
+		default:
+			return v >= n.num
+		}
+	}
+
+	v := n.f.str(e, n.env)
+	switch n.op {
+	case "~":
+		return strings.Contains(strings.ToLower(v), n.str)
+	case "!~":
+		return !strings.Contains(strings.ToLower(v), n.str)
+	}
+	equal := strings.EqualFold(v, n.str)
+	if n.f.match != nil {
+		equal = n.f.match(e, n.str)
+	}
+	if n.op == "!=" {
+		return !equal
+	}
+	return equal
+}
+
+// Tokens.
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp // a comparison operator
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	col  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// lex splits an expression into tokens. Bare words run until whitespace
+// or a character that starts an operator, so addresses like ::1 and
+// 10.0.0.0 need no quotes.
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		col := i + 1
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", col})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", col})
+			i++
+		case strings.HasPrefix(src[i:], "&&"):
+			toks = append(toks, token{tokAnd, "&&", col})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			toks = append(toks, token{tokOr, "||", col})
+			i += 2
+		case c == '&' || c == '|':
+			return nil, &Error{col, fmt.Sprintf("expected %q", string(c)+string(c))}
+		case strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "<="), strings.HasPrefix(src[i:], ">="),
+			strings.HasPrefix(src[i:], "!~"):
+			toks = append(toks, token{tokOp, src[i : i+2], col})
+			i += 2
+		case c == '<' || c == '>' || c == '~':
+			toks = append(toks, token{tokOp, string(c), col})
+			i++
+		case c == '=':
+			return nil, &Error{col, `expected "==" to compare`}
+		case c == '!':
+			toks = append(toks, token{tokNot, "!", col})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end == -1 {
+				return nil, &Error{col, "unterminated string"}
+			}
+			toks = append(toks, token{tokString, src[i+1 : i+1+end], col})
+			i += end + 2
+		default:
+			start := i
+			for i < len(src) && !strings.ContainsRune(" \t()&|=!<>~\"'", rune(src[i])) {
+				i++
+			}
+			toks = append(toks, token{tokWord, src[start:i], col})
+		}
+	}
+	return append(toks, token{tokEOF, "", len(src) + 1}), nil
+}
+
+// Parser.
+
+type parser struct {
+	toks []token
+	pos  int
+	env  *Env
+	refs map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &Error{t.col, fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses and-expressions joined by ||.
+func (p *parser) parseOr() (node, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orNode{l, r}
+	}
+	return l, nil
+}
+
+// parseAnd parses unary expressions joined by &&.
+func (p *parser) parseAnd() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andNode{l, r}
+	}
+	return l, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a
+// comparison.
+func (p *parser) parseUnary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, p.errorf(r, "expected \")\" to close \"(\" at column %d, got %s", t.col, r)
+		}
+		return x, nil
+	case tokWord:
+		return p.parseComparison(t)
+	}
+	return nil, p.errorf(t, "expected a field name, got %s", t)
+}
+
+// parseComparison parses "field op value" once the field has been read.
+func (p *parser) parseComparison(name token) (node, error) {
+	key := strings.ToLower(name.text)
+	if alias, ok := fieldAliases[key]; ok {
+		key = alias
+	}
+	f, ok := fields[key]
+	if !ok {
+		return nil, p.errorf(name, "unknown field %q (valid: %s)", name.text, fieldNames())
+	}
+	p.refs[key] = true
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, p.errorf(op, "expected a comparison after %q, got %s", name.text, op)
+	}
+	val := p.next()
+	if val.kind != tokWord && val.kind != tokString {
+		return nil, p.errorf(val, "expected a value after %q, got %s", op.text, val)
+	}
+
+	n := cmpNode{f: f, op: op.text, env: p.env}
+	if f.num != nil {
+		if op.text == "~" || op.text == "!~" {
+			return nil, p.errorf(op, "%q cannot be used with numeric field %q", op.text, name.text)
+		}
+		num, err := p.number(key, val)
+		if err != nil {
+			return nil, err
+		}
+		n.num = num
+		return n, nil
+	}
+
+	switch op.text {
+	case "<", "<=", ">", ">=":
+		return nil, p.errorf(op, "%q needs a numeric field, and %q is not one", op.text, name.text)
+	}
+	n.str = val.text
+	switch {
+	case val.kind == tokWord && val.text == "me" && key == "user":
+		n.str = p.env.User
+	case key == "state" && op.text != "~" && op.text != "!~":
+		st, ok := port.ParseState(val.text)
+		if !ok {
+			return nil, p.errorf(val, "unknown state %q", val.text)
+		}
+		n.str = string(st)
+	case key == "bind" && (val.text == "0.0.0.0" || val.text == "::"):
+		n.str = "*"
+	}
+	if op.text == "~" || op.text == "!~" {
+		n.str = strings.ToLower(n.str)
+	}
+	return n, nil
+}
+
+// number parses the value of a numeric field. Ports may also be given as
+// service names.
+func (p *parser) number(key string, val token) (int, error) {
+	if n, err := strconv.Atoi(val.text); err == nil {
+		return n, nil
+	}
+	if key == "port" || key == "remote_port" {
+		if n, ok := p.env.Services.Port(val.text); ok {
+			return n, nil
+		}
+		return 0, p.errorf(val, "expected a port number or service name, got %s", val)
+	}
+	return 0, p.errorf(val, "expected a number, got %s", val)
+}
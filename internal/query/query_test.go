@@ -0,0 +1,156 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+var entries = []port.PortEntry{
+	{Port: 3000, Protocol: port.TCP, PID: 100, Process: "node", User: "alice", Command: "node server.js", State: port.StateListen, LocalAddr: "*", Family: port.DualStack},
+	{Port: 5432, Protocol: port.TCP, PID: 200, Process: "postgres", User: "postgres", State: port.StateListen, LocalAddr: "127.0.0.1", Family: port.IPv4},
+	{Port: 5173, Protocol: port.TCP, PID: 300, Process: "node", User: "alice", Command: "vite --port 5173", State: port.StateListen, LocalAddr: "::1", Family: port.IPv6},
+	{Port: 51234, Protocol: port.TCP, PID: 100, Process: "node", User: "alice", State: port.StateEstablished, LocalAddr: "10.0.0.5", RemoteAddr: "10.0.5.20", RemotePort: 5432},
+	{Port: 53, Protocol: port.UDP, PID: 400, Process: "dnsmasq", User: "nobody", State: port.StateListen, LocalAddr: "*", Family: port.IPv4},
+	{Port: 8080, Protocol: port.TCP, PID: 500, Process: "docker-proxy", User: "root", State: port.StateListen, LocalAddr: "*", Container: port.Container{ID: "3f4a1c9e8b7d6a5f", Runtime: "docker"}},
+}
+
+func testEnv() Env {
+	services := port.NewServiceResolver(map[int]string{5173: "vite", 5432: "postgresql"})
+	return Env{User: "alice", Services: services}
+}
+
+// matches returns the ports of the entries the expression matches.
+func matches(t *testing.T, src string) []int {
+	t.Helper()
+	x, err := Parse(src, testEnv())
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", src, err)
+	}
+	var ports []int
+	for _, e := range entries {
+		if x.Match(e) {
+			ports = append(ports, e.Port)
+		}
+	}
+	return ports
+}
+
+func TestParse_Match(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []int
+	}{
+		{"user == me && port >= 3000 && state == LISTEN && bind != 127.0.0.1", []int{3000, 5173}},
+		{"port == 5432", []int{5432}},
+		{"port == postgres", []int{5432}},
+		{"remote_port == postgresql", []int{51234}},
+		{"port < 1024", []int{53}},
+		{"port > 5000 && port <= 5432", []int{5432, 5173}},
+		{"process == NODE && state == established", []int{51234}},
+		{"state == listening && proto == udp", []int{53}},
+		{"protocol != tcp", []int{53}},
+		{"command ~ vite", []int{5173}},
+		{"command !~ vite && user == alice", []int{3000, 51234}},
+		{"process == node || user == root", []int{3000, 5173, 51234, 8080}},
+		{"!(process == node) && port != 53", []int{5432, 8080}},
+		{"user == postgres || user == nobody && port == 53", []int{5432, 53}},
+		{"(user == postgres || user == nobody) && port == 53", []int{53}},
+		{"bind == 0.0.0.0", []int{3000, 53, 8080}},
+		{"bind == ::1", []int{5173}},
+		{"family == ipv6 || family == dual", []int{3000, 5173}},
+		{"remote == '10.0.5.20:5432'", []int{51234}},
+		{"container == docker", []int{8080}},
+		{"container == 3f4a", []int{8080}},
+		{"container ~ docker:", []int{8080}},
+		{"service == vite", []int{5173}},
+		{`user == "me"`, nil},
+		{"pid == 100", []int{3000, 51234}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got := matches(t, tt.src)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		col  int
+		want string
+	}{
+		{"", 1, "expected a field name"},
+		{"port", 5, "expected a comparison"},
+		{"port ==", 8, "expected a value"},
+		{"prot == tcp", 1, "unknown field"},
+		{"port == 80 &&", 14, "expected a field name"},
+		{"port == 80 & user == me", 12, `expected "&&"`},
+		{"port = 80", 6, `expected "=="`},
+		{"port == http-ish", 9, "port number or service name"},
+		{"pid == abc", 8, "expected a number"},
+		{"port ~ 80", 6, "numeric field"},
+		{"user > bob", 6, "needs a numeric field"},
+		{"state == sleeping", 10, "unknown state"},
+		{"(port == 80 || pid == 1", 24, `to close "(" at column 1`},
+		{"port == 80)", 11, `unexpected ")"`},
+		{"user == 'bob", 9, "unterminated string"},
+		{"port == 80 port == 81", 12, `unexpected "port"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src, testEnv())
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a parse error, got %v", err)
+			}
+			if perr.Col != tt.col || !strings.Contains(perr.Msg, tt.want) {
+				t.Errorf("got %q at column %d, want %q at column %d", perr.Msg, perr.Col, tt.want, tt.col)
+			}
+		})
+	}
+}
+
+func TestExpr_Nil(t *testing.T) {
+	var x *Expr
+	if !x.Match(entries[0]) {
+		t.Error("expected a nil expression to match everything")
+	}
+}
+
+func TestIsExpression(t *testing.T) {
+	for s, want := range map[string]bool{
+		"node":           false,
+		"3000-3999":      false,
+		"port >= 3000":   true,
+		"user==me":       true,
+		"command ~ vite": true,
+	} {
+		if got := IsExpression(s); got != want {
+			t.Errorf("IsExpression(%q): got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestExpr_References(t *testing.T) {
+	x, err := Parse("protocol == tcp && !(remote_port == 5432)", testEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !x.References("state", "remote_port") || !x.References("proto") {
+		t.Error("expected the compared fields to be referenced")
+	}
+	if x.References("state", "user") {
+		t.Error("expected other fields not to be referenced")
+	}
+}
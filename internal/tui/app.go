@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/user"
 	"sort"
@@ -13,7 +14,10 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/lu-zhengda/whport/internal/port"
+	"github.com/lu-zhengda/whport/internal/probe"
 	"github.com/lu-zhengda/whport/internal/process"
+	"github.com/lu-zhengda/whport/internal/procwatch"
+	"github.com/lu-zhengda/whport/internal/query"
 )
 
 // viewState tracks which screen the TUI is currently showing.
@@ -38,8 +42,9 @@ const (
 
 // Messages for async operations.
 type scanDoneMsg struct {
-	entries []port.PortEntry
-	err     error
+	gen  int // the scanGen the scan was started as
+	snap *port.Snapshot
+	err  error
 }
 
 type tickMsg time.Time
@@ -52,6 +57,11 @@ type killDoneMsg struct {
 	forced  bool
 }
 
+// probeDoneMsg carries probe results keyed by probeKey.
+type probeDoneMsg struct {
+	results map[string]*probe.Result
+}
+
 type infoDoneMsg struct {
 	info *process.ProcessInfo
 	err  error
@@ -59,9 +69,15 @@ type infoDoneMsg struct {
 
 // Model is the main Bubbletea model for the whport TUI.
 type Model struct {
-	scanner  *port.LsofScanner
+	scanner  port.Scanner
+	backend  string
 	manager  *process.RealManager
+	services *port.ServiceResolver
 	version  string
+	refresh  *procwatch.Trigger
+	timeout  time.Duration  // per scan; 0 disables
+	snap     *port.Snapshot // last successful scan
+	scanErr  error          // error from the last scan, if it failed
 	entries  []port.PortEntry
 	filtered []int // indices into entries for currently displayed items
 
@@ -70,7 +86,19 @@ type Model struct {
 	sortBy       sortField
 	searching    bool
 	searchQuery  string
+	searchErr    error // why the query failed to parse as an expression
 	paused       bool
+	showAll      bool // include established connections
+
+	// forwards holds the ports relayed by ssh, kubectl and socat
+	// processes, keyed by PID, for the table's forward badges.
+	forwards map[int][]process.Forward
+
+	// Probe state. Each listener is probed once; probes holds the
+	// results, including nil for listeners that were not identified.
+	probing      bool
+	probeRunning bool
+	probes       map[string]*probe.Result
 
 	// Info view state.
 	infoEntry *port.PortEntry
@@ -84,6 +112,7 @@ type Model struct {
 
 	currentUser string
 	scanning    bool
+	scanGen     int // counts scans started; results of older scans are dropped
 	spinner     spinner.Model
 
 	width  int
@@ -93,7 +122,9 @@ type Model struct {
 }
 
 // New creates a new TUI model.
-func New(scanner *port.LsofScanner, manager *process.RealManager, version string) Model {
+// The refresh trigger paces automatic rescans, and scans that take longer
+// than timeout are abandoned. Ports are named by services.
+func New(scanner port.Scanner, backend string, manager *process.RealManager, services *port.ServiceResolver, refresh *procwatch.Trigger, timeout time.Duration, version string) Model {
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
 	sp.Style = lipgloss.NewStyle().Foreground(colorCyan)
@@ -105,10 +136,15 @@ func New(scanner *port.LsofScanner, manager *process.RealManager, version string
 
 	return Model{
 		scanner:     scanner,
+		backend:     backend,
 		manager:     manager,
+		services:    services,
+		refresh:     refresh,
+		timeout:     timeout,
 		version:     version,
 		currentUser: currentUser,
-		scanning:    true,
+		scanning:    true, // the scan started by Init
+		scanGen:     1,
 		spinner:     sp,
 		currentView: viewTable,
 	}
@@ -116,39 +152,73 @@ func New(scanner *port.LsofScanner, manager *process.RealManager, version string
 
 // Init starts the spinner and kicks off the initial scan.
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, m.doScan(), tickCmd())
+	return tea.Batch(m.spinner.Tick, m.doScan(), m.waitRefresh())
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
+// waitRefresh waits until the refresh trigger says a rescan is due.
+func (m Model) waitRefresh() tea.Cmd {
+	c := m.refresh.C
+	return func() tea.Msg {
+		<-c
+		return tickMsg(time.Now())
+	}
+}
+
+// startScan starts a scan with the current options. A scan still running
+// is superseded: its result is dropped when it arrives.
+func (m *Model) startScan() tea.Cmd {
+	m.scanGen++
+	m.scanning = true
+	return m.doScan()
 }
 
 func (m Model) doScan() tea.Cmd {
+	opts := port.SnapshotOptions{All: m.showAll}
+	gen := m.scanGen
 	return func() tea.Msg {
-		ctx := context.Background()
-		entries, err := m.scanner.ListPorts(ctx)
-		return scanDoneMsg{entries: entries, err: err}
+		ctx, cancel := m.context()
+		defer cancel()
+		snap, err := port.TakeSnapshot(ctx, m.scanner, m.backend, opts)
+		return scanDoneMsg{gen: gen, snap: snap, err: err}
 	}
 }
 
-func (m Model) doScanAll() tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		entries, err := m.scanner.ListAllPorts(ctx)
-		return scanDoneMsg{entries: entries, err: err}
+// context returns a context for one scan or kill, bounded by the timeout.
+func (m Model) context() (context.Context, context.CancelFunc) {
+	if m.timeout <= 0 {
+		return context.WithCancel(context.Background())
 	}
+	return context.WithTimeout(context.Background(), m.timeout)
+}
+
+// snapshotForwards parses the command lines of forwarding processes in a
+// snapshot, so tunnels can be told apart from ordinary servers.
+func snapshotForwards(snap *port.Snapshot) map[int][]process.Forward {
+	forwards := make(map[int][]process.Forward)
+	for _, e := range snap.Entries {
+		if !process.IsForwarder(e.Process) {
+			continue
+		}
+		if _, ok := forwards[e.PID]; ok {
+			continue
+		}
+		if p, ok := snap.Process(e.PID); ok {
+			forwards[e.PID] = process.ParseForwards(p.Command)
+		}
+	}
+	return forwards
 }
 
 func (m Model) doKill(pid int, processName string, portNum int, force bool) tea.Cmd {
 	mgr := m.manager
 	return func() tea.Msg {
+		ctx, cancel := m.context()
+		defer cancel()
 		if force {
-			err := mgr.ForceKill(pid)
+			err := mgr.ForceKill(ctx, pid)
 			return killDoneMsg{pid: pid, process: processName, port: portNum, err: err, forced: true}
 		}
-		exited, err := mgr.GracefulKill(pid)
+		exited, err := mgr.GracefulKill(ctx, pid)
 		if err != nil {
 			return killDoneMsg{pid: pid, process: processName, port: portNum, err: err}
 		}
@@ -164,11 +234,58 @@ func (m Model) doKill(pid int, processName string, portNum int, force bool) tea.
 	}
 }
 
+// probeKey identifies a listener across scans.
+func probeKey(e port.PortEntry) (string, bool) {
+	addr, ok := probe.Target(e)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d %s", e.PID, addr), true
+}
+
+// doProbe probes the listeners that have not been probed yet. Remote
+// listeners are not reachable the way they are bound, so they are not
+// probed.
+func (m Model) doProbe() tea.Cmd {
+	if m.snap == nil || m.snap.Host != "" {
+		return nil
+	}
+	var todo []port.PortEntry
+	var keys []string
+	seen := make(map[string]bool)
+	for _, e := range m.entries {
+		key, ok := probeKey(e)
+		if !ok || seen[key] {
+			continue
+		}
+		if _, done := m.probes[key]; done {
+			continue
+		}
+		seen[key] = true
+		todo = append(todo, e)
+		keys = append(keys, key)
+	}
+	if len(todo) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := m.context()
+		defer cancel()
+		results := make(map[string]*probe.Result, len(todo))
+		for i, r := range probe.Entries(ctx, todo, probe.DefaultTimeout) {
+			results[keys[i]] = r
+		}
+		return probeDoneMsg{results: results}
+	}
+}
+
 func (m Model) doGetInfo(pid int) tea.Cmd {
-	mgr := m.manager
+	snap := m.snap
 	return func() tea.Msg {
-		ctx := context.Background()
-		info, err := mgr.Info(ctx, pid)
+		if snap == nil {
+			return infoDoneMsg{err: fmt.Errorf("no scan yet")}
+		}
+		info, err := process.SnapshotInfo(snap, pid)
 		return infoDoneMsg{info: info, err: err}
 	}
 }
@@ -190,17 +307,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tickMsg:
-		if !m.paused && m.currentView == viewTable {
-			return m, tea.Batch(m.doScan(), tickCmd())
+		// A scan that is still running is not started again, so a slow
+		// backend cannot pile up scans.
+		if !m.paused && !m.scanning && m.currentView == viewTable {
+			return m, tea.Batch(m.startScan(), m.waitRefresh())
 		}
-		return m, tickCmd()
+		return m, m.waitRefresh()
 
 	case scanDoneMsg:
+		if msg.gen != m.scanGen {
+			// Superseded by a scan started since, such as after "a".
+			return m, nil
+		}
 		m.scanning = false
+		m.scanErr = msg.err
 		if msg.err == nil {
-			m.entries = msg.entries
+			m.snap = msg.snap
+			m.entries = msg.snap.Entries
+			m.forwards = snapshotForwards(msg.snap)
 			m.sortEntries()
 			m.rebuildFiltered()
+			if m.probing && !m.probeRunning {
+				if cmd := m.doProbe(); cmd != nil {
+					m.probeRunning = true
+					return m, cmd
+				}
+			}
+		}
+		return m, nil
+
+	case probeDoneMsg:
+		m.probeRunning = false
+		if m.probes == nil {
+			m.probes = make(map[string]*probe.Result)
+		}
+		for k, r := range msg.results {
+			m.probes[k] = r
 		}
 		return m, nil
 
@@ -270,14 +412,27 @@ func (m Model) updateTable(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, m.doGetInfo(entry.PID)
 		}
 	case "r":
-		m.scanning = true
-		return m, tea.Batch(m.doScan(), m.spinner.Tick)
+		// A scan already running has the current options.
+		if !m.scanning {
+			return m, tea.Batch(m.startScan(), m.spinner.Tick)
+		}
 	case "s":
 		m.sortBy = (m.sortBy + 1) % 3
 		m.sortEntries()
 		m.rebuildFiltered()
 	case "p":
 		m.paused = !m.paused
+	case "P":
+		m.probing = !m.probing
+		if m.probing && !m.probeRunning {
+			if cmd := m.doProbe(); cmd != nil {
+				m.probeRunning = true
+				return m, cmd
+			}
+		}
+	case "a":
+		m.showAll = !m.showAll
+		return m, tea.Batch(m.startScan(), m.spinner.Tick)
 	case "/":
 		m.currentView = viewFilter
 		m.searchQuery = ""
@@ -338,8 +493,7 @@ func (m Model) updateKillResult(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.killResult = ""
 		m.killErr = nil
 		// Refresh after kill.
-		m.scanning = true
-		return m, tea.Batch(m.doScan(), m.spinner.Tick)
+		return m, tea.Batch(m.startScan(), m.spinner.Tick)
 	}
 	return m, nil
 }
@@ -397,17 +551,11 @@ func (m *Model) sortEntries() {
 
 func (m *Model) rebuildFiltered() {
 	m.filtered = m.filtered[:0]
-	query := strings.ToLower(m.searchQuery)
+	match, err := m.searchMatcher()
+	m.searchErr = err
 	for i, e := range m.entries {
-		if query != "" {
-			match := strings.Contains(strings.ToLower(e.Process), query) ||
-				strings.Contains(strings.ToLower(e.User), query) ||
-				strings.Contains(strings.ToLower(e.Command), query) ||
-				strings.Contains(fmt.Sprintf("%d", e.Port), query) ||
-				strings.Contains(fmt.Sprintf("%d", e.PID), query)
-			if !match {
-				continue
-			}
+		if err != nil || (match != nil && !match(e)) {
+			continue
 		}
 		m.filtered = append(m.filtered, i)
 	}
@@ -417,6 +565,51 @@ func (m *Model) rebuildFiltered() {
 	m.adjustScroll()
 }
 
+// searchMatcher returns the test the search query applies to entries, or
+// nil without a query. A query with a comparison is a filter expression,
+// one like "3000-3999" a port set, and anything else is matched as text.
+func (m *Model) searchMatcher() (func(port.PortEntry) bool, error) {
+	if m.searchQuery == "" {
+		return nil, nil
+	}
+	if query.IsExpression(m.searchQuery) {
+		x, err := query.Parse(m.searchQuery, query.Env{User: m.currentUser, Services: m.services})
+		if err != nil {
+			return nil, err
+		}
+		return x.Match, nil
+	}
+	if ports := m.portQuery(); ports != nil {
+		return func(e port.PortEntry) bool { return ports.Match(e.Port) }, nil
+	}
+
+	text := strings.ToLower(m.searchQuery)
+	return func(e port.PortEntry) bool {
+		return strings.Contains(strings.ToLower(e.Process), text) ||
+			strings.Contains(strings.ToLower(e.User), text) ||
+			strings.Contains(strings.ToLower(e.Command), text) ||
+			strings.Contains(fmt.Sprintf("%d", e.Port), text) ||
+			strings.Contains(m.services.Name(e.Port, e.Protocol), text) ||
+			strings.Contains(fmt.Sprintf("%d", e.PID), text) ||
+			strings.Contains(strings.ToLower(e.Remote()), text) ||
+			strings.Contains(e.Container.String(), text)
+	}, nil
+}
+
+// portQuery returns the search query as a port set when it looks like one,
+// such as "3000-3999" or "postgres,redis". Other queries, including plain
+// numbers, are matched as text.
+func (m *Model) portQuery() *port.PortSet {
+	if !strings.ContainsAny(m.searchQuery, ",-!") {
+		return nil
+	}
+	set, err := port.ParsePortSet(m.searchQuery, m.services)
+	if err != nil {
+		return nil
+	}
+	return set
+}
+
 func (m *Model) ensureCursorVisible() {
 	visible := m.visibleRows()
 	if m.cursor < m.scrollOffset {
@@ -473,14 +666,25 @@ func (m Model) View() string {
 	}
 }
 
+// scanErrorText describes a failed scan for the header bar.
+func scanErrorText(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "[scan timed out]"
+	}
+	return "[scan failed]"
+}
+
 func (m Model) viewTable() string {
 	var b strings.Builder
 
 	// Header bar.
 	title := titleStyle.Render(fmt.Sprintf("whport %s", m.version))
+	if m.snap != nil && m.snap.Host != "" {
+		title += warnStyle.Render("  " + m.snap.Host)
+	}
 	listenCount := 0
 	for _, e := range m.entries {
-		if e.State == "LISTEN" {
+		if e.State == port.StateListen {
 			listenCount++
 		}
 	}
@@ -489,6 +693,15 @@ func (m Model) viewTable() string {
 	if m.paused {
 		pauseIndicator = warnStyle.Render("  [PAUSED]")
 	}
+	if m.showAll {
+		pauseIndicator += dimStyle.Render("  [ALL]")
+	}
+	if !m.paused && m.refresh.Events() {
+		pauseIndicator += dimStyle.Render("  [LIVE]")
+	}
+	if m.scanErr != nil {
+		pauseIndicator += errorStyle.Render("  " + scanErrorText(m.scanErr))
+	}
 	b.WriteString(title + "  " + stats + pauseIndicator + "\n")
 
 	if m.scanning && len(m.entries) == 0 {
@@ -503,19 +716,48 @@ func (m Model) viewTable() string {
 		}
 		return ""
 	}
+	// The REMOTE column is only useful when connections are shown.
+	remoteHeader := ""
+	if m.showAll {
+		remoteHeader = fmt.Sprintf("%-21s ", "REMOTE")
+	}
+	// Likewise CONTAINER, once any listener runs in a container.
+	showContainer := false
+	for _, e := range m.entries {
+		if !e.Container.IsZero() {
+			showContainer = true
+			break
+		}
+	}
+	containerHeader := ""
+	if showContainer {
+		containerHeader = fmt.Sprintf("%-19s ", "CONTAINER")
+	}
+	// PROTOCOL/APP is shown while probing.
+	probeHeader := ""
+	if m.probing {
+		probeHeader = fmt.Sprintf("%-24s ", "PROTOCOL/APP")
+	}
 	b.WriteString(headerStyle.Render(fmt.Sprintf(
-		"  %-7s %-6s %-7s %-16s %-11s %-13s %s",
+		"  %-7s %-6s %-14s %-15s %s%s%-7s %-16s %-11s %-13s %s%s",
 		"PORT"+sortIndicator(sortByPort),
 		"PROTO",
+		"SERVICE",
+		"BIND",
+		remoteHeader,
+		containerHeader,
 		"PID"+sortIndicator(sortByPID),
 		"PROCESS"+sortIndicator(sortByProcess),
 		"USER",
 		"STATE",
+		probeHeader,
 		"COMMAND",
 	)) + "\n")
 
 	if len(m.filtered) == 0 {
-		if m.searchQuery != "" {
+		if m.searchErr != nil {
+			b.WriteString("\n  " + errorStyle.Render("Invalid filter: "+m.searchErr.Error()) + "\n")
+		} else if m.searchQuery != "" {
 			b.WriteString("\n  No results matching: " + m.searchQuery + "\n")
 		} else {
 			b.WriteString("\n  No listening ports found.\n")
@@ -538,24 +780,56 @@ func (m Model) viewTable() string {
 
 			// Truncate command to fit.
 			cmd := e.Command
-			maxCmdLen := m.width - 60
+			maxCmdLen := m.width - 91
+			remote := ""
+			if m.showAll {
+				remote = fmt.Sprintf("%-21s ", truncate(e.Remote(), 21))
+				maxCmdLen -= 22
+			}
+			container := ""
+			if showContainer {
+				container = fmt.Sprintf("%-19s ", e.Container.String())
+				maxCmdLen -= 20
+			}
+			app := ""
+			if m.probing {
+				app = fmt.Sprintf("%-24s ", truncate(m.probeLabel(e), 24))
+				maxCmdLen -= 25
+			}
 			if maxCmdLen < 10 {
 				maxCmdLen = 10
 			}
+			badge := ""
+			if f := process.ForwardFor(m.forwards[e.PID], e.Port); f != nil {
+				badge = fmt.Sprintf("[-> %s] ", f.Target)
+				maxCmdLen -= len(badge)
+				if maxCmdLen < 10 {
+					maxCmdLen = 10
+				}
+			}
 			if len(cmd) > maxCmdLen {
 				cmd = cmd[:maxCmdLen-3] + "..."
 			}
 
 			style := processStyle(e.User)
-			line := fmt.Sprintf("%-7d %-6s %-7d %-16s %-11s %-13s %s",
-				e.Port, e.Protocol, e.PID,
+			service := m.services.Name(e.Port, e.Protocol)
+			if service == "" {
+				service = "-"
+			}
+			line := fmt.Sprintf("%-7d %-6s %-14s %-15s %s%s%-7d %-16s %-11s %-13s %s",
+				e.Port, e.Protocol,
+				truncate(service, 14),
+				truncate(e.Bind(), 15),
+				remote,
+				container,
+				e.PID,
 				truncate(e.Process, 16),
 				truncate(e.User, 11),
 				e.State,
-				cmd,
+				app,
 			)
 
-			b.WriteString(cursor + style.Render(line) + "\n")
+			b.WriteString(cursor + style.Render(line) + forwardBadgeStyle.Render(badge) + style.Render(cmd) + "\n")
 		}
 
 		// Scroll indicator.
@@ -571,11 +845,25 @@ func (m Model) viewTable() string {
 	}
 
 	// Help bar.
-	b.WriteString(helpStyle.Render("j/k:navigate  K:kill  i:info  r:refresh  s:sort  p:pause  /:search  q:quit") + "\n")
+	b.WriteString(helpStyle.Render("j/k:navigate  K:kill  i:info  r:refresh  s:sort  a:all  p:pause  P:probe  /:search  q:quit") + "\n")
 
 	return b.String()
 }
 
+// probeLabel returns the PROTOCOL/APP cell for an entry: "..." while its
+// probe runs and "-" if it was not identified or cannot be probed.
+func (m Model) probeLabel(e port.PortEntry) string {
+	key, ok := probeKey(e)
+	if !ok || m.snap == nil || m.snap.Host != "" {
+		return "-"
+	}
+	r, done := m.probes[key]
+	if !done {
+		return "..."
+	}
+	return r.String()
+}
+
 func (m Model) viewInfo() string {
 	var b strings.Builder
 
@@ -595,8 +883,40 @@ func (m Model) viewInfo() string {
 
 	e := m.infoEntry
 	b.WriteString(labelStyle.Render("Port:") + valueStyle.Render(fmt.Sprintf("%d/%s", e.Port, e.Protocol)) + "\n")
-	b.WriteString(labelStyle.Render("State:") + valueStyle.Render(e.State) + "\n")
+	if service := m.services.Name(e.Port, e.Protocol); service != "" {
+		b.WriteString(labelStyle.Render("Service:") + valueStyle.Render(service) + "\n")
+	}
+	b.WriteString(labelStyle.Render("State:") + valueStyle.Render(string(e.State)) + "\n")
+	if bind := e.BindSummary(); bind != "" {
+		b.WriteString(labelStyle.Render("Bind:") + valueStyle.Render(bind) + "\n")
+	}
+	if remote := e.Remote(); remote != "" {
+		b.WriteString(labelStyle.Render("Remote:") + valueStyle.Render(remote) + "\n")
+	}
+	if m.infoData != nil {
+		if f := process.ForwardFor(m.infoData.Forwards, e.Port); f != nil {
+			b.WriteString(labelStyle.Render("Forwards to:") + forwardBadgeStyle.Render(f.String()) + "\n")
+		}
+	}
+	if c := e.Container; !c.IsZero() {
+		b.WriteString(labelStyle.Render("Container:") + valueStyle.Render(fmt.Sprintf("%s (%s)", c.ShortID(), c.Runtime)) + "\n")
+		if c.Pod != "" {
+			b.WriteString(labelStyle.Render("Pod:") + valueStyle.Render(c.Pod) + "\n")
+		}
+	}
 	b.WriteString(labelStyle.Render("Process:") + valueStyle.Render(fmt.Sprintf("%s (PID %d)", e.Process, e.PID)) + "\n")
+	if key, ok := probeKey(*e); ok && m.probes[key] != nil {
+		r := m.probes[key]
+		b.WriteString(labelStyle.Render("Probe:") + valueStyle.Render(r.String()) + "\n")
+		if r.Status != "" {
+			b.WriteString(labelStyle.Render("Status:") + valueStyle.Render(r.Status) + "\n")
+		}
+		if r.TLSSubject != "" {
+			b.WriteString(labelStyle.Render("Certificate:") + valueStyle.Render(
+				fmt.Sprintf("%s, expires %s", r.TLSSubject, r.TLSExpiry.Format("2006-01-02")),
+			) + "\n")
+		}
+	}
 
 	if m.infoData != nil {
 		info := m.infoData
@@ -676,7 +996,14 @@ func (m Model) viewFilter() string {
 	var b strings.Builder
 
 	b.WriteString(titleStyle.Render("whport -- Search") + "\n\n")
-	b.WriteString("  Type to filter: " + m.searchQuery + "_\n")
+	const prompt = "  Type to filter: "
+	b.WriteString(prompt + m.searchQuery + "_\n")
+	var perr *query.Error
+	if errors.As(m.searchErr, &perr) {
+		// Point at the column the expression went wrong in.
+		b.WriteString(strings.Repeat(" ", len(prompt)+perr.Col-1) + errorStyle.Render("^ "+perr.Msg) + "\n")
+	}
+	b.WriteString(dimStyle.Render("\n  Text, ports like 3000-3999,!3306, or an expression like user == me && port >= 3000") + "\n")
 	b.WriteString(helpStyle.Render("\nenter:apply  esc:cancel") + "\n")
 
 	return b.String()
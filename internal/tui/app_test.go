@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lu-zhengda/whport/internal/port"
+)
+
+func key(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func update(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+	next, _ := m.Update(msg)
+	return next.(Model)
+}
+
+func TestToggleAllDuringScan(t *testing.T) {
+	listeners := &port.Snapshot{Entries: []port.PortEntry{
+		{Port: 3000, Protocol: port.TCP, State: port.StateListen, PID: 1, Process: "node"},
+	}}
+	all := &port.Snapshot{Entries: []port.PortEntry{
+		{Port: 3000, Protocol: port.TCP, State: port.StateListen, PID: 1, Process: "node"},
+		{Port: 3000, Protocol: port.TCP, State: port.StateEstablished, PID: 1, Process: "node", RemotePort: 51000},
+	}}
+
+	// The initial listener scan is still running when "a" is pressed.
+	m := New(nil, "test", nil, nil, nil, 0, "test")
+	first := m.scanGen
+	m = update(t, m, key("a"))
+	if !m.showAll || !m.scanning {
+		t.Fatalf("expected a scan of all connections to be running")
+	}
+
+	// The listener scan finishing last must not replace the [ALL] view.
+	m = update(t, m, scanDoneMsg{gen: m.scanGen, snap: all})
+	m = update(t, m, scanDoneMsg{gen: first, snap: listeners})
+	if len(m.entries) != 2 {
+		t.Errorf("entries: got %d, want the 2 from the scan of all connections", len(m.entries))
+	}
+	if m.scanning {
+		t.Error("expected scanning to be over")
+	}
+}
+
+func TestToggleAllDuringScan_StaleFirst(t *testing.T) {
+	listeners := &port.Snapshot{Entries: []port.PortEntry{
+		{Port: 3000, Protocol: port.TCP, State: port.StateListen, PID: 1, Process: "node"},
+	}}
+
+	m := New(nil, "test", nil, nil, nil, 0, "test")
+	first := m.scanGen
+	m = update(t, m, key("a"))
+
+	// The superseded scan finishing first leaves the new one running.
+	m = update(t, m, scanDoneMsg{gen: first, snap: listeners})
+	if len(m.entries) != 0 {
+		t.Errorf("entries: got %d, want none from the superseded scan", len(m.entries))
+	}
+	if !m.scanning {
+		t.Error("expected the scan of all connections to still be running")
+	}
+}
+
+func TestRescanDuringScan(t *testing.T) {
+	m := New(nil, "test", nil, nil, nil, 0, "test")
+	gen := m.scanGen
+
+	// "r" while a scan with the same options runs does not start another.
+	m = update(t, m, key("r"))
+	if m.scanGen != gen {
+		t.Errorf("scanGen: got %d, want %d", m.scanGen, gen)
+	}
+
+	m = update(t, m, scanDoneMsg{gen: gen, snap: &port.Snapshot{}})
+	if m.scanning {
+		t.Fatal("expected scanning to be over")
+	}
+	m = update(t, m, key("r"))
+	if m.scanGen != gen+1 || !m.scanning {
+		t.Errorf("expected \"r\" to start a scan once idle")
+	}
+}
@@ -71,6 +71,12 @@ var (
 
 	valueStyle = lipgloss.NewStyle().
 			Foreground(colorWhite)
+
+	// forwardBadgeStyle marks listeners that relay to somewhere else,
+	// such as ssh tunnels.
+	forwardBadgeStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(colorYellow)
 )
 
 // processStyle returns the appropriate style based on the process owner.